@@ -0,0 +1,208 @@
+// Package anthropic provides an llm.Provider backed by Anthropic's Messages API.
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/samredway/scrapeai/llm"
+)
+
+const (
+	anthropicApiUrl  = "https://api.anthropic.com/v1/messages"
+	anthropicVersion = "2023-06-01"
+	defaultModel     = "claude-haiku-4-5"
+	defaultMaxTokens = 4096
+	// the time limit for each request, long enough for slow responses but
+	// short enough that a stalled connection does not hang a scrape forever
+	defaultHTTPTimeout = 5 * time.Minute
+)
+
+// defaultHTTPClient is used for requests unless WithHTTPClient is given
+var defaultHTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+
+// functional options for Provider
+type Option func(*Provider)
+
+// Allows overriding the Messages API endpoint, eg to point at a test server
+func WithApiUrl(url string) Option {
+	return func(p *Provider) {
+		p.apiUrl = url
+	}
+}
+
+// Allows specifying the API key. The default is read from ANTHROPIC_API_KEY
+func WithApiKey(key string) Option {
+	return func(p *Provider) {
+		p.apiKey = key
+	}
+}
+
+// Allows specifying the model used for extraction
+func WithModel(model string) Option {
+	return func(p *Provider) {
+		p.model = model
+	}
+}
+
+// Allows specifying the maximum number of tokens the model may generate
+func WithMaxTokens(maxTokens int) Option {
+	return func(p *Provider) {
+		p.maxTokens = maxTokens
+	}
+}
+
+// Allows specifying the HTTP client used for requests, eg to set timeouts,
+// a custom transport or a proxy. The default client times out each request
+// after 5 minutes
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// Provider implements llm.Provider on top of the Anthropic Messages API
+type Provider struct {
+	apiUrl    string
+	apiKey    string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+// NewProvider returns an Anthropic backed llm.Provider with sensible defaults
+func NewProvider(options ...Option) *Provider {
+	p := &Provider{
+		apiUrl:    anthropicApiUrl,
+		model:     defaultModel,
+		maxTokens: defaultMaxTokens,
+		client:    defaultHTTPClient,
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// Extract asks the model to call the scrape_result tool with the schema as its
+// input schema and returns the tool input as the JSON result
+func (p *Provider) Extract(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	maxTokens := p.maxTokens
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	request := NewMessageRequest(p.model, maxTokens, req.Prompt, req.Page, req.Schema)
+	if req.Repair != nil {
+		// the rejected tool input is quoted back in a single user turn as
+		// replaying the tool call would also require a tool result
+		request.Messages[0].Content += "\n\nYour previous call to the " + toolName +
+			" tool had the input:\n" + req.Repair.Response + "\n\n" +
+			llm.RepairInstructions(req.Repair.Problem)
+	}
+
+	response, err := p.SendMessageRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &llm.Response{
+		Usage: llm.Usage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
+		},
+		Model:        response.Model,
+		ID:           response.ID,
+		FinishReason: response.StopReason,
+	}
+	if response.StopReason == "refusal" {
+		result.Refusal = response.Text()
+		if result.Refusal == "" {
+			result.Refusal = "the model declined to respond"
+		}
+		return result, nil
+	}
+
+	input, ok := response.ToolInput(toolName)
+	if !ok && !result.Truncated() {
+		return nil, fmt.Errorf("response did not contain a %s tool call (stop reason %s)", toolName, response.StopReason)
+	}
+	result.Content = string(input)
+	return result, nil
+}
+
+// Model returns the name of the model requests are sent to
+func (p *Provider) Model() string {
+	return p.model
+}
+
+// SendMessageRequest sends a request to the Messages API and returns the
+// decoded MessageResponse
+func (p *Provider) SendMessageRequest(ctx context.Context, config *MessageRequest) (*MessageResponse, error) {
+	apiKey := p.apiKey
+	if apiKey == "" {
+		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY is not set in the environment")
+	}
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.apiUrl, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error with request to Anthropic API unable to read body of response")
+		}
+		return nil, newApiError(resp.StatusCode, body)
+	}
+
+	var messageResponse MessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&messageResponse); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return &messageResponse, nil
+}
+
+// newApiError builds an llm.APIError from a failed response
+func newApiError(statusCode int, body []byte) *llm.APIError {
+	var errorBody struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &errorBody)
+	return &llm.APIError{
+		Provider:   "Anthropic",
+		StatusCode: statusCode,
+		Type:       errorBody.Error.Type,
+		Message:    errorBody.Error.Message,
+		Body:       string(body),
+	}
+}
@@ -0,0 +1,51 @@
+package anthropic
+
+import (
+	"encoding/json"
+)
+
+// Name of the tool the model is forced to call with the extracted data
+const toolName = "scrape_result"
+
+type MessageRequest struct {
+	Model       string     `json:"model"`
+	MaxTokens   int        `json:"max_tokens"`
+	Temperature float64    `json:"temperature"`
+	Messages    []Message  `json:"messages"`
+	Tools       []Tool     `json:"tools"`
+	ToolChoice  ToolChoice `json:"tool_choice"`
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// NewMessageRequest creates a new Messages API request with the given prompt,
+// page and schema. The model is forced to call a single tool whose input
+// schema is the requested schema, so the tool input is the structured result
+func NewMessageRequest(model string, maxTokens int, prompt, page, schema string) *MessageRequest {
+	return &MessageRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: 0.0,
+		Messages:    []Message{{Role: "user", Content: prompt + "\n\n" + page}},
+		Tools: []Tool{{
+			Name:        toolName,
+			Description: "Record the data extracted from the page",
+			InputSchema: json.RawMessage(schema),
+		}},
+		ToolChoice: ToolChoice{Type: "tool", Name: toolName},
+	}
+}
@@ -0,0 +1,50 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type MessageResponse struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Role       string         `json:"role"`
+	Model      string         `json:"model"`
+	Content    []ContentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      UsageInfo      `json:"usage"`
+}
+
+type ContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type UsageInfo struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ToolInput returns the input of the first call to the named tool
+func (r *MessageResponse) ToolInput(name string) (json.RawMessage, bool) {
+	for _, block := range r.Content {
+		if block.Type == "tool_use" && block.Name == name {
+			return block.Input, true
+		}
+	}
+	return nil, false
+}
+
+// Text returns the text blocks of the response joined together
+func (r *MessageResponse) Text() string {
+	var parts []string
+	for _, block := range r.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
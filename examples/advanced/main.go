@@ -53,8 +53,30 @@ func main() {
 		log.Fatalf("Failed to convert result to ReturnData: %v", err)
 	}
 
-	fmt.Printf("Custom Schema Result:\nHeadline: %s\nBody: %s\n",
+	fmt.Printf("Custom Schema Result:\nHeadline: %s\nBody: %s\n\n",
 		content.Headline,
 		content.Body,
 	)
+
+	// Example: Typed results with the schema generated from the struct
+	fmt.Println("=== Typed Result Example ===")
+	type Article struct {
+		Headline string   `json:"headline" description:"The main headline of the page"`
+		Body     string   `json:"body"`
+		Links    []string `json:"links" description:"Text of any links on the page"`
+	}
+
+	article, _, err := scrapeai.ScrapeInto[Article](ctx, url,
+		"Extract the headline, body content and links",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+	)
+	if err != nil {
+		log.Fatalf("Error scraping with AI: %v", err)
+	}
+
+	fmt.Printf("Typed Result:\nHeadline: %s\nBody: %s\nLinks: %v\n",
+		article.Headline,
+		article.Body,
+		article.Links,
+	)
 }
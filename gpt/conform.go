@@ -0,0 +1,301 @@
+package gpt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ValidateResponse checks that content is JSON which conforms to the given
+// schema, including constraints such as patterns, formats and numeric ranges
+// which the API does not enforce. Errors report the JSON pointer path of the
+// offending value
+func ValidateResponse(schema, content string) error {
+	var schemaObj map[string]any
+	if err := json.Unmarshal([]byte(schema), &schemaObj); err != nil {
+		return fmt.Errorf("schema is not valid json: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return fmt.Errorf("response is not valid json: %w", err)
+	}
+	c := conformer{root: schemaObj}
+	return c.check(schemaObj, value, "")
+}
+
+type conformer struct {
+	root map[string]any
+}
+
+func (c conformer) check(schema map[string]any, value any, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := c.resolve(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pointer(path), err)
+		}
+		return c.check(resolved, value, path)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		for _, option := range anyOf {
+			if optionMap, ok := option.(map[string]any); ok {
+				if c.check(optionMap, value, path) == nil {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("%s: value does not match any of the anyOf schemas", pointer(path))
+	}
+
+	if constValue, ok := schema["const"]; ok && !jsonEqual(constValue, value) {
+		return fmt.Errorf("%s: value %v does not equal the constant %v", pointer(path), value, constValue)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, value) }) {
+			return fmt.Errorf("%s: value %v is not one of the allowed enum values", pointer(path), value)
+		}
+	}
+
+	types := schemaTypes(schema["type"])
+	if len(types) == 0 {
+		return nil
+	}
+	valueType := jsonType(value)
+	if !slices.Contains(types, valueType) && !(valueType == "integer" && slices.Contains(types, "number")) {
+		return fmt.Errorf("%s: expected %s but got %s", pointer(path), strings.Join(types, " or "), valueType)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return c.checkObject(schema, v, path)
+	case []any:
+		return c.checkArray(schema, v, path)
+	case string:
+		return checkStringValue(schema, v, path)
+	case float64:
+		return checkNumberValue(schema, v, path)
+	}
+	return nil
+}
+
+func (c conformer) checkArray(schema map[string]any, arr []any, path string) error {
+	if minItems, ok := schema["minItems"].(float64); ok && float64(len(arr)) < minItems {
+		return fmt.Errorf("%s: expected at least %v items but got %d", pointer(path), minItems, len(arr))
+	}
+	if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(arr)) > maxItems {
+		return fmt.Errorf("%s: expected at most %v items but got %d", pointer(path), maxItems, len(arr))
+	}
+	items, ok := schema["items"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for i, item := range arr {
+		if err := c.check(items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkStringValue(schema map[string]any, s string, path string) error {
+	if pattern, ok := schema["pattern"].(string); ok {
+		// patterns are written for ECMAScript regular expressions, those which
+		// Go cannot compile are skipped rather than rejecting the response
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+			return fmt.Errorf("%s: value %q does not match the pattern %s", pointer(path), s, pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok {
+		if check, known := formatCheckers[format]; known && !check(s) {
+			return fmt.Errorf("%s: value %q is not a valid %s", pointer(path), s, format)
+		}
+	}
+	return nil
+}
+
+func checkNumberValue(schema map[string]any, n float64, path string) error {
+	if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+		return fmt.Errorf("%s: value %v is less than the minimum %v", pointer(path), n, minimum)
+	}
+	if maximum, ok := schema["maximum"].(float64); ok && n > maximum {
+		return fmt.Errorf("%s: value %v is greater than the maximum %v", pointer(path), n, maximum)
+	}
+	if minimum, ok := schema["exclusiveMinimum"].(float64); ok && n <= minimum {
+		return fmt.Errorf("%s: value %v must be greater than %v", pointer(path), n, minimum)
+	}
+	if maximum, ok := schema["exclusiveMaximum"].(float64); ok && n >= maximum {
+		return fmt.Errorf("%s: value %v must be less than %v", pointer(path), n, maximum)
+	}
+	if multipleOf, ok := schema["multipleOf"].(float64); ok && multipleOf > 0 {
+		quotient := n / multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			return fmt.Errorf("%s: value %v is not a multiple of %v", pointer(path), n, multipleOf)
+		}
+	}
+	return nil
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+	durationPattern = regexp.MustCompile(`^P(\d+Y)?(\d+M)?(\d+W)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+)
+
+// formatCheckers validate the string formats supported in strict mode
+var formatCheckers = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	},
+	"time": func(s string) bool {
+		for _, layout := range []string{"15:04:05Z07:00", time.TimeOnly} {
+			if _, err := time.Parse(layout, s); err == nil {
+				return true
+			}
+		}
+		return false
+	},
+	"duration": func(s string) bool {
+		return s != "P" && !strings.HasSuffix(s, "T") && durationPattern.MatchString(s)
+	},
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"hostname": func(s string) bool {
+		return len(s) <= 253 && hostnamePattern.MatchString(s)
+	},
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	},
+	"uuid": uuidPattern.MatchString,
+}
+
+func (c conformer) checkObject(schema map[string]any, obj map[string]any, path string) error {
+	props, _ := schema["properties"].(map[string]any)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			key, _ := r.(string)
+			if _, exists := obj[key]; !exists {
+				return fmt.Errorf("%s: missing required property %s", pointer(path), key)
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(obj) {
+		propSchema, defined := props[key].(map[string]any)
+		if !defined {
+			if schema["additionalProperties"] == false {
+				return fmt.Errorf("%s: unexpected property %s", pointer(path), key)
+			}
+			continue
+		}
+		if err := c.check(propSchema, obj[key], path+"/"+escapePointer(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve looks up a local reference of the form #/$defs/name
+func (c conformer) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local references are supported, got %s", ref)
+	}
+	var node any = c.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		nodeMap, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unable to resolve reference %s", ref)
+		}
+		node, ok = nodeMap[unescapePointer(part)]
+		if !ok {
+			return nil, fmt.Errorf("unable to resolve reference %s", ref)
+		}
+	}
+	resolved, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("reference %s does not point to a schema", ref)
+	}
+	return resolved, nil
+}
+
+// schemaTypes normalises the type keyword which may be a string or a list
+func schemaTypes(t any) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonType returns the JSON schema type name of a decoded JSON value
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonEqual(a, b any) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+// pointer renders a JSON pointer path, using / for the document root
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+}
@@ -2,49 +2,108 @@ package gpt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"time"
 )
 
 const openaiApiUrl = "https://api.openai.com/v1/chat/completions"
 
+// the time limit for each attempt at a request, long enough for slow models
+// generating large responses but short enough that a stalled connection does
+// not hang a scrape forever
+const defaultHTTPTimeout = 5 * time.Minute
+
+// defaultHTTPClient is used for requests unless WithHTTPClient is given
+var defaultHTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+
 // SendGptRequest sends a GPT request to the OpenAI API and returns a GptResponse
-// TODO: Handle size limits (chunking strategy)
 func SendGptRequest(config *GptRequest) (*GptResponse, error) {
+	return SendGptRequestWithContext(context.Background(), config)
+}
+
+// SendGptRequestWithContext is SendGptRequest with a context which cancels
+// the request, including any retries in progress
+func SendGptRequestWithContext(ctx context.Context, config *GptRequest) (*GptResponse, error) {
 	openaiApiKey := os.Getenv("OPENAI_API_KEY")
 	if openaiApiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY is not set in the environment")
 	}
+	headers := http.Header{"Authorization": {"Bearer " + openaiApiKey}}
+	return sendRequest(ctx, defaultHTTPClient, openaiApiUrl, headers, DefaultRetryPolicy, config)
+}
 
+// sendRequest posts the request to any OpenAI compatible chat completions
+// endpoint with the given auth headers, retrying transient failures according
+// to the retry policy, and decodes the response
+func sendRequest(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	headers http.Header,
+	policy RetryPolicy,
+	config *GptRequest,
+) (*GptResponse, error) {
 	body, err := json.Marshal(config)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", openaiApiUrl, bytes.NewBuffer(body))
+	for retry := 0; ; retry++ {
+		response, err := doRequest(ctx, client, url, headers, body)
+		if err == nil {
+			return response, nil
+		}
+		delay, ok := policy.backoff(retry, err)
+		if !ok || ctx.Err() != nil {
+			return nil, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("waiting to retry: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// doRequest makes a single attempt at the request
+func doRequest(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	headers http.Header,
+	body []byte,
+) (*GptResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+openaiApiKey)
+	for key, values := range headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %w", err)
+		return nil, &transportError{fmt.Errorf("error sending request: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("error with request to GPT API unable to read body of response")
+			return nil, &transportError{fmt.Errorf("error with request to GPT API unable to read body of response")}
 		}
-		return nil, fmt.Errorf("GPT API request failed with status code: %d and message %s", resp.StatusCode, body)
+		return nil, newApiError(resp, body)
 	}
 
 	var gptResponse GptResponse
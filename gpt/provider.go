@@ -0,0 +1,178 @@
+package gpt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/samredway/scrapeai/llm"
+)
+
+// functional options for Provider
+type Option func(*Provider)
+
+// Allows pointing the provider at any OpenAI compatible server such as vLLM,
+// llama.cpp server, LM Studio or Ollama, eg http://localhost:11434/v1. The
+// /chat/completions path is appended to the base URL
+func WithBaseUrl(url string) Option {
+	return func(p *Provider) {
+		p.baseUrl = strings.TrimSuffix(url, "/")
+	}
+}
+
+// Allows specifying the API key sent as a bearer token. When using the default
+// OpenAI endpoint this falls back to OPENAI_API_KEY
+func WithApiKey(key string) Option {
+	return func(p *Provider) {
+		p.headers.Set("Authorization", "Bearer "+key)
+	}
+}
+
+// Allows specifying an arbitrary auth header for servers that do not use
+// bearer tokens, eg WithAuthHeader("X-Api-Key", key)
+func WithAuthHeader(name, value string) Option {
+	return func(p *Provider) {
+		p.headers.Set(name, value)
+	}
+}
+
+// Allows specifying the model used for extraction. The default is gpt-4o-mini
+func WithModel(model string) Option {
+	return func(p *Provider) {
+		p.model = model
+	}
+}
+
+// Allows specifying how the schema is sent to the server. For any mode other
+// than SchemaModeJSONSchema the server cannot be relied on to enforce the
+// schema, Scrape validates every response against it after it is received
+func WithSchemaMode(mode SchemaMode) Option {
+	return func(p *Provider) {
+		p.schemaMode = mode
+	}
+}
+
+// Enables checking each response against the schema in Extract, returning an
+// error when it does not match. Scrape already validates and repairs every
+// response, this is for callers using the provider directly with a schema
+// mode the server cannot be relied on to enforce
+func WithResponseValidation() Option {
+	return func(p *Provider) {
+		p.validate = true
+	}
+}
+
+// Allows specifying how transient failures such as rate limits are retried.
+// The default is DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Provider) {
+		p.retryPolicy = policy
+	}
+}
+
+// Allows specifying the HTTP client used for requests, eg to set timeouts,
+// a custom transport or a proxy. The default client times out each attempt
+// after 5 minutes
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// Provider implements llm.Provider on top of the OpenAI chat completions API
+// or any server which is compatible with it
+type Provider struct {
+	baseUrl     string
+	headers     http.Header
+	model       string
+	schemaMode  SchemaMode
+	retryPolicy RetryPolicy
+	client      *http.Client
+	validate    bool
+}
+
+// NewProvider returns an OpenAI backed llm.Provider
+func NewProvider(options ...Option) *Provider {
+	p := &Provider{
+		headers:     http.Header{},
+		model:       defaultModel,
+		retryPolicy: DefaultRetryPolicy,
+		client:      defaultHTTPClient,
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// Extract sends the prompt, page and schema to the server and returns the raw
+// JSON content of the first choice along with the usage reported by the API
+func (p *Provider) Extract(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	schema := req.Schema
+	if schema == "" {
+		schema = DefaultSchemaTemplate
+	}
+
+	gptRequest := NewGptRequest(req.Prompt, req.Page)
+	gptRequest.Model = p.model
+	if p.baseUrl == "" {
+		gptRequest.MaxCompletionTokens = req.MaxTokens
+	} else {
+		gptRequest.MaxTokens = req.MaxTokens
+	}
+	gptRequest.ApplySchema(p.schemaMode, schema)
+	if req.Repair != nil {
+		gptRequest.Messages = append(gptRequest.Messages,
+			GptMessage{Role: "assistant", Content: req.Repair.Response},
+			GptMessage{Role: "user", Content: llm.RepairInstructions(req.Repair.Problem)},
+		)
+	}
+
+	response, err := p.send(ctx, gptRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("response %s contained no choices", response.ID)
+	}
+	if p.validate {
+		if err := ValidateResponse(schema, response.Choices[0].Message.Content); err != nil {
+			return nil, fmt.Errorf("response does not match schema: %w", err)
+		}
+	}
+
+	return &llm.Response{
+		Content: response.Choices[0].Message.Content,
+		Usage: llm.Usage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		},
+		Model:        response.Model,
+		ID:           response.ID,
+		FinishReason: response.Choices[0].FinishReason,
+		Refusal:      response.Choices[0].Message.Refusal,
+	}, nil
+}
+
+// Model returns the name of the model requests are sent to
+func (p *Provider) Model() string {
+	return p.model
+}
+
+func (p *Provider) send(ctx context.Context, config *GptRequest) (*GptResponse, error) {
+	if p.baseUrl == "" {
+		headers := p.headers.Clone()
+		if headers.Get("Authorization") == "" {
+			openaiApiKey := os.Getenv("OPENAI_API_KEY")
+			if openaiApiKey == "" {
+				return nil, fmt.Errorf("OPENAI_API_KEY is not set in the environment")
+			}
+			headers.Set("Authorization", "Bearer "+openaiApiKey)
+		}
+		return sendRequest(ctx, p.client, openaiApiUrl, headers, p.retryPolicy, config)
+	}
+	return sendRequest(ctx, p.client, p.baseUrl+"/chat/completions", p.headers, p.retryPolicy, config)
+}
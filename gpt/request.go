@@ -16,22 +16,67 @@ const DefaultSchemaTemplate = `{
     "required": ["data"]
 }`
 
+const defaultModel = "gpt-4o-mini"
+
+// SchemaMode controls how the response schema is communicated to the server.
+// OpenAI supports json_schema response formats natively, but many local and
+// OpenAI compatible servers only support one of the alternatives
+type SchemaMode int
+
+const (
+	// response_format of type json_schema with strict mode (OpenAI)
+	SchemaModeJSONSchema SchemaMode = iota
+	// response_format of type json_object with the schema given in the prompt
+	SchemaModeJSONObject
+	// response_format of type json_schema without strict mode, which Ollama and
+	// llama.cpp server use to constrain the output to any valid JSON schema
+	SchemaModeFormat
+	// top level guided_json field holding the schema (vLLM)
+	SchemaModeGuidedJSON
+)
+
 type GptRequest struct {
-	Model          string         `json:"model"`
-	Temperature    float64        `json:"temperature"`
-	Seed           int            `json:"seed"`
-	Messages       []GptMessage   `json:"messages"`
-	ResponseFormat ResponseFormat `json:"response_format"`
+	Model       string       `json:"model"`
+	Temperature float64      `json:"temperature"`
+	Seed        int          `json:"seed"`
+	Messages    []GptMessage `json:"messages"`
+	MaxTokens   int          `json:"max_tokens,omitempty"` // For OpenAI compatible servers
+	// Output limit for the OpenAI API, which replaces max_tokens and is the
+	// only limit accepted by reasoning models such as o1 and o3-mini
+	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"`
+	ResponseFormat      *ResponseFormat `json:"response_format,omitempty"`
+	GuidedJSON          json.RawMessage `json:"guided_json,omitempty"`
+}
+
+// MarshalJSON leaves out the temperature for reasoning models, which reject
+// any value other than their default
+func (r *GptRequest) MarshalJSON() ([]byte, error) {
+	type request GptRequest
+	if !IsReasoningModel(r.Model) {
+		return json.Marshal((*request)(r))
+	}
+	return json.Marshal(struct {
+		*request
+		Temperature *float64 `json:"temperature,omitempty"`
+	}{request: (*request)(r)})
+}
+
+// IsReasoningModel reports whether a model is one of OpenAI's o-series
+// reasoning models, which do not accept sampling parameters such as
+// temperature
+func IsReasoningModel(model string) bool {
+	return len(model) >= 2 && model[0] == 'o' && model[1] >= '1' && model[1] <= '9'
 }
 
 type ResponseFormat struct {
-	Type       string     `json:"type"`
-	JSONSchema JsonSchema `json:"json_schema"`
+	Type       string      `json:"type"`
+	JSONSchema *JsonSchema `json:"json_schema,omitempty"`
 }
 
 type GptMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	Refusal string `json:"refusal,omitempty"` // Set instead of content when the model declines
 }
 
 type JsonSchema struct {
@@ -40,25 +85,22 @@ type JsonSchema struct {
 	Schema json.RawMessage `json:"schema"`
 }
 
-var defaultRequest = GptRequest{
-	Model:       "gpt-4o-mini",
-	Temperature: 0.0,
-	Seed:        42,
-	ResponseFormat: ResponseFormat{
-		Type: "json_schema",
-		JSONSchema: JsonSchema{
-			Name:   "scrape_result",
-			Strict: true,
-			Schema: DefaultSchema(),
-		},
-	},
-}
-
 // NewGptRequest creates a new GPT request with the given prompt and page
 func NewGptRequest(prompt, page string) *GptRequest {
-	request := defaultRequest
-	request.Messages = []GptMessage{{Role: "user", Content: prompt + "\n\n" + page}}
-	return &request
+	return &GptRequest{
+		Model:       defaultModel,
+		Temperature: 0.0,
+		Seed:        42,
+		Messages:    []GptMessage{{Role: "user", Content: prompt + "\n\n" + page}},
+		ResponseFormat: &ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &JsonSchema{
+				Name:   "scrape_result",
+				Strict: true,
+				Schema: DefaultSchema(),
+			},
+		},
+	}
 }
 
 // DefaultSchema returns the default schema configuration which is a array of strings
@@ -70,5 +112,43 @@ func DefaultSchema() json.RawMessage {
 // The schema can be pre-validated using ValidateSchema()
 // before being set to avoid potential runtime errors.
 func (r *GptRequest) SetSchema(schema string) {
-	r.ResponseFormat.JSONSchema.Schema = json.RawMessage(schema)
+	r.ApplySchema(SchemaModeJSONSchema, schema)
+}
+
+// ApplySchema sets the response schema using the given mode. For every mode
+// other than SchemaModeJSONSchema the schema is also appended to the first
+// message as servers in those modes may not pass it on to the model
+func (r *GptRequest) ApplySchema(mode SchemaMode, schema string) {
+	r.ResponseFormat = nil
+	r.GuidedJSON = nil
+
+	switch mode {
+	case SchemaModeJSONSchema:
+		r.ResponseFormat = &ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &JsonSchema{
+				Name:   "scrape_result",
+				Strict: true,
+				Schema: json.RawMessage(schema),
+			},
+		}
+		return
+	case SchemaModeJSONObject:
+		r.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	case SchemaModeFormat:
+		r.ResponseFormat = &ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &JsonSchema{
+				Name:   "scrape_result",
+				Schema: json.RawMessage(schema),
+			},
+		}
+	case SchemaModeGuidedJSON:
+		r.GuidedJSON = json.RawMessage(schema)
+	}
+
+	if len(r.Messages) > 0 {
+		r.Messages[0].Content = "Respond only with JSON matching this schema:\n" +
+			schema + "\n\n" + r.Messages[0].Content
+	}
 }
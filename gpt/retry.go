@@ -0,0 +1,130 @@
+package gpt
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samredway/scrapeai/llm"
+)
+
+// RetryPolicy controls how requests which fail with a transient error are
+// retried. Rate limits (429), server errors (5xx) and network errors are
+// retried, other errors are returned immediately
+type RetryPolicy struct {
+	MaxRetries int           // Number of retries after the first attempt
+	BaseDelay  time.Duration // Delay before the first retry, doubled each time
+	MaxDelay   time.Duration // Upper bound for any single delay
+}
+
+// DefaultRetryPolicy retries up to three times starting at half a second
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// transportError wraps network failures which may succeed if retried
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// retryable reports whether the request may succeed if sent again
+func retryable(e *llm.APIError) bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		// running out of quota is reported as a rate limit but will not
+		// resolve itself by waiting
+		return e.Code != "insufficient_quota"
+	case e.StatusCode == http.StatusRequestTimeout, e.StatusCode >= 500:
+		return true
+	}
+	return false
+}
+
+// newApiError builds an llm.APIError from a failed response
+func newApiError(resp *http.Response, body []byte) *llm.APIError {
+	var errorBody struct {
+		Error struct {
+			Code    string `json:"code"`
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &errorBody)
+	return &llm.APIError{
+		Provider:   "GPT",
+		StatusCode: resp.StatusCode,
+		Code:       errorBody.Error.Code,
+		Type:       errorBody.Error.Type,
+		Message:    errorBody.Error.Message,
+		Body:       string(body),
+		RetryAfter: retryAfter(resp.Header),
+	}
+}
+
+// retryAfter returns the delay requested by the server from the Retry-After
+// headers, or the time until the exhausted OpenAI rate limit resets
+func retryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil {
+		return time.Duration(ms) * time.Millisecond
+	}
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(time.Until(at), 0)
+		}
+	}
+	var delay time.Duration
+	for _, limit := range []string{"requests", "tokens"} {
+		if header.Get("x-ratelimit-remaining-"+limit) != "0" {
+			continue
+		}
+		if reset, err := time.ParseDuration(header.Get("x-ratelimit-reset-" + limit)); err == nil {
+			delay = max(delay, reset)
+		}
+	}
+	return delay
+}
+
+// backoff returns how long to wait before the given retry and whether the
+// error should be retried at all. Server requested delays are honoured, but
+// if they exceed MaxDelay there is no point waiting and the error is returned
+func (p RetryPolicy) backoff(retry int, err error) (time.Duration, bool) {
+	if retry >= p.MaxRetries {
+		return 0, false
+	}
+	var apiErr *llm.APIError
+	var transportErr *transportError
+	switch {
+	case errors.As(err, &apiErr):
+		if !retryable(apiErr) {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, apiErr.RetryAfter <= p.MaxDelay
+		}
+	case !errors.As(err, &transportErr):
+		return 0, false
+	}
+	// exponential backoff with equal jitter
+	delay := min(p.BaseDelay<<retry, p.MaxDelay)
+	if delay <= 0 {
+		return 0, true
+	}
+	half := delay / 2
+	return half + rand.N(half+1), true
+}
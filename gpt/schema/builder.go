@@ -0,0 +1,187 @@
+package schema
+
+// The functions and methods below build schemas by hand, for example
+//
+//	schema.Object().
+//		Prop("name", schema.String().Desc("Name of the product")).
+//		Prop("price", schema.Number().Desc("Price in US dollars").Nullable()).
+//		Prop("tags", schema.Array(schema.String()))
+//
+// Objects always disallow additional properties and require every property,
+// as strict mode demands. Fields which may be missing should be made
+// nullable instead.
+
+// Object returns an object schema with no properties. Add properties with Prop
+func Object() *Schema {
+	return newSchema().
+		set("type", "object").
+		set("properties", newSchema()).
+		set("additionalProperties", false).
+		set("required", []string{})
+}
+
+// String returns a string schema
+func String() *Schema {
+	return newSchema().set("type", "string")
+}
+
+// Number returns a number schema
+func Number() *Schema {
+	return newSchema().set("type", "number")
+}
+
+// Integer returns an integer schema
+func Integer() *Schema {
+	return newSchema().set("type", "integer")
+}
+
+// Boolean returns a boolean schema
+func Boolean() *Schema {
+	return newSchema().set("type", "boolean")
+}
+
+// Array returns an array schema whose items match the given schema
+func Array(items *Schema) *Schema {
+	return newSchema().set("type", "array").set("items", items)
+}
+
+// AnyOf returns a schema matching any of the given schemas
+func AnyOf(options ...*Schema) *Schema {
+	return newSchema().set("anyOf", options)
+}
+
+// Ref returns a reference to a schema added to the root with Def. An empty
+// name refers to the root schema itself, for recursive structures
+func Ref(name string) *Schema {
+	if name == "" {
+		return newSchema().set("$ref", "#")
+	}
+	return newSchema().set("$ref", "#/$defs/"+name)
+}
+
+// Prop adds a required property to an object schema. Properties are emitted in
+// the order they are added and adding an existing name replaces it
+func (s *Schema) Prop(name string, prop *Schema) *Schema {
+	props, ok := s.values["properties"].(*Schema)
+	if !ok {
+		props = newSchema()
+		s.set("properties", props)
+	}
+	if _, exists := props.get(name); !exists {
+		required, _ := s.values["required"].([]string)
+		s.set("required", append(required, name))
+	}
+	props.set(name, prop)
+	s.set("additionalProperties", false)
+	return s
+}
+
+// Def adds a named schema to $defs for use with Ref. Definitions belong on
+// the root schema
+func (s *Schema) Def(name string, def *Schema) *Schema {
+	defs, ok := s.values["$defs"].(*Schema)
+	if !ok {
+		defs = newSchema()
+		s.set("$defs", defs)
+	}
+	defs.set(name, def)
+	return s
+}
+
+// Desc sets the description of the schema, which is shown to the model
+func (s *Schema) Desc(description string) *Schema {
+	return s.set("description", description)
+}
+
+// Enum restricts the schema to the given values. A nullable schema also
+// allows null
+func (s *Schema) Enum(values ...any) *Schema {
+	values = append([]any{}, values...)
+	if isNullable(s) && !containsNil(values) {
+		values = append(values, nil)
+	}
+	return s.set("enum", values)
+}
+
+// Nullable allows null as well as the schema. Strings, numbers, integers and
+// booleans become a type list so the schema is modified in place, while
+// objects and arrays are wrapped in an anyOf which is returned. Call Nullable
+// after the schema is otherwise complete
+func (s *Schema) Nullable() *Schema {
+	if isNullable(s) {
+		return s
+	}
+	if values, ok := s.values["enum"].([]any); ok && !containsNil(values) {
+		s.set("enum", append(values, nil))
+	}
+	return nullable(s)
+}
+
+// Pattern restricts a string schema to values matching a regular expression
+func (s *Schema) Pattern(pattern string) *Schema {
+	return s.set("pattern", pattern)
+}
+
+// Format restricts a string schema to a format such as "date-time", "email"
+// or "uuid"
+func (s *Schema) Format(format string) *Schema {
+	return s.set("format", format)
+}
+
+// Minimum sets the inclusive minimum of a number or integer schema
+func (s *Schema) Minimum(n float64) *Schema {
+	return s.set("minimum", n)
+}
+
+// Maximum sets the inclusive maximum of a number or integer schema
+func (s *Schema) Maximum(n float64) *Schema {
+	return s.set("maximum", n)
+}
+
+// ExclusiveMinimum sets the exclusive minimum of a number or integer schema
+func (s *Schema) ExclusiveMinimum(n float64) *Schema {
+	return s.set("exclusiveMinimum", n)
+}
+
+// ExclusiveMaximum sets the exclusive maximum of a number or integer schema
+func (s *Schema) ExclusiveMaximum(n float64) *Schema {
+	return s.set("exclusiveMaximum", n)
+}
+
+// MultipleOf restricts a number or integer schema to multiples of n
+func (s *Schema) MultipleOf(n float64) *Schema {
+	return s.set("multipleOf", n)
+}
+
+// MinItems sets the minimum length of an array schema
+func (s *Schema) MinItems(n int) *Schema {
+	return s.set("minItems", n)
+}
+
+// MaxItems sets the maximum length of an array schema
+func (s *Schema) MaxItems(n int) *Schema {
+	return s.set("maxItems", n)
+}
+
+// isNullable reports whether the schema's type list already includes null
+func isNullable(s *Schema) bool {
+	types, ok := s.values["type"].([]string)
+	if !ok {
+		return false
+	}
+	for _, t := range types {
+		if t == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+func containsNil(values []any) bool {
+	for _, value := range values {
+		if value == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawType       = reflect.TypeFor[json.RawMessage]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// For generates a strict mode schema for T, which must be a struct. See
+// FromType for how Go types map to the schema
+func For[T any]() (*Schema, error) {
+	return FromType(reflect.TypeFor[T]())
+}
+
+// FromType generates a strict mode schema for a struct type.
+//
+// Exported fields are named by their json tag and fields tagged "-" are
+// skipped. Every property is required as strict mode demands, so omitempty has
+// no effect; pointer fields are nullable instead. Embedded structs are
+// flattened as encoding/json does. Fields may carry a description tag, and an
+// enum tag holding a comma separated list of allowed values.
+//
+// Strings, bools, numbers, slices, arrays, structs and time.Time (as an RFC
+// 3339 string) are supported. Maps, interfaces and channels cannot be
+// expressed in strict mode and return an error. Recursive types are emitted as
+// references into $defs
+func FromType(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil, fmt.Errorf("the root of a schema must be a struct, got %s", t)
+	}
+
+	g := &generator{
+		root:       t,
+		inProgress: map[reflect.Type]bool{},
+		recursive:  map[reflect.Type]bool{},
+		defs:       newSchema(),
+	}
+	root, err := g.schemaFor(t, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(g.defs.keys) > 0 {
+		root.set("$defs", g.defs)
+	}
+	return root, nil
+}
+
+type generator struct {
+	root       reflect.Type
+	inProgress map[reflect.Type]bool
+	recursive  map[reflect.Type]bool
+	defs       *Schema
+}
+
+func (g *generator) schemaFor(t reflect.Type, path string) (*Schema, error) {
+	if t == timeType {
+		return newSchema().set("type", "string").set("description", "RFC 3339 date-time"), nil
+	}
+	if t == rawType || (t.Kind() != reflect.Struct && t.Implements(marshalerType)) {
+		return nil, fmt.Errorf("%s: type %s uses custom JSON encoding and cannot be described", pathOrRoot(path), t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return newSchema().set("type", "string"), nil
+	case reflect.Bool:
+		return newSchema().set("type", "boolean"), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return newSchema().set("type", "integer"), nil
+	case reflect.Float32, reflect.Float64:
+		return newSchema().set("type", "number"), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes byte slices as base64 strings
+			return newSchema().set("type", "string"), nil
+		}
+		items, err := g.schemaFor(t.Elem(), path+"/items")
+		if err != nil {
+			return nil, err
+		}
+		return newSchema().set("type", "array").set("items", items), nil
+	case reflect.Pointer:
+		inner, err := g.schemaFor(t.Elem(), path)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(inner), nil
+	case reflect.Struct:
+		return g.structSchema(t, path)
+	}
+	return nil, fmt.Errorf("%s: type %s cannot be expressed in a strict mode schema", pathOrRoot(path), t)
+}
+
+// structSchema returns the object schema for a struct, or a reference to it
+// if the struct contains itself
+func (g *generator) structSchema(t reflect.Type, path string) (*Schema, error) {
+	if g.inProgress[t] {
+		g.recursive[t] = true
+		return g.ref(t), nil
+	}
+	g.inProgress[t] = true
+	defer delete(g.inProgress, t)
+
+	obj := newSchema().set("type", "object")
+	props := newSchema()
+	required := []string{}
+	if err := g.addFields(t, props, &required, path); err != nil {
+		return nil, err
+	}
+	obj.set("properties", props)
+	obj.set("additionalProperties", false)
+	obj.set("required", required)
+
+	if g.recursive[t] && t != g.root {
+		g.defs.set(defName(t), obj)
+		return g.ref(t), nil
+	}
+	return obj, nil
+}
+
+// addFields adds the properties for the fields of a struct, flattening
+// embedded structs into the parent
+func (g *generator) addFields(t reflect.Type, props *Schema, required *[]string, path string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && !hasJSONName(field) {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+				if err := g.addFields(fieldType, props, required, path); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := fieldName(field)
+
+		propPath := path + "/properties/" + name
+		prop, err := g.schemaFor(fieldType, propPath)
+		if err != nil {
+			return err
+		}
+		if err := applyTags(prop, field, fieldType, propPath); err != nil {
+			return err
+		}
+		if _, exists := props.get(name); !exists {
+			*required = append(*required, name)
+		}
+		props.set(name, prop)
+	}
+	return nil
+}
+
+// applyTags adds the description and enum tags of a field to its schema
+func applyTags(prop *Schema, field reflect.StructField, t reflect.Type, path string) error {
+	if desc := field.Tag.Get("description"); desc != "" {
+		prop.set("description", desc)
+	}
+	enumTag := field.Tag.Get("enum")
+	if enumTag == "" {
+		return nil
+	}
+
+	isNullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		isNullable = true
+	}
+	values := []any{}
+	for _, raw := range strings.Split(enumTag, ",") {
+		raw = strings.TrimSpace(raw)
+		value, err := enumValue(t, raw)
+		if err != nil {
+			return fmt.Errorf("%s: invalid enum value %q: %w", path, raw, err)
+		}
+		values = append(values, value)
+	}
+	if isNullable {
+		values = append(values, nil)
+	}
+	prop.set("enum", values)
+	return nil
+}
+
+// enumValue parses an enum tag value as the kind of the field
+func enumValue(t reflect.Type, raw string) (any, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	}
+	return nil, fmt.Errorf("enums are not supported for %s", t)
+}
+
+// nullable allows null as well as the given schema. Simple types use a type
+// list, anything else an anyOf
+func nullable(s *Schema) *Schema {
+	if t, ok := s.get("type"); ok {
+		if name, isString := t.(string); isString && name != "object" && name != "array" {
+			return s.set("type", []string{name, "null"})
+		}
+	}
+	return newSchema().set("anyOf", []*Schema{s, newSchema().set("type", "null")})
+}
+
+func (g *generator) ref(t reflect.Type) *Schema {
+	if t == g.root {
+		return newSchema().set("$ref", "#")
+	}
+	return newSchema().set("$ref", "#/$defs/"+defName(t))
+}
+
+func defName(t reflect.Type) string {
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return strings.NewReplacer(" ", "", ".", "_").Replace(t.String())
+}
+
+// fieldName returns the JSON name of a field
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func hasJSONName(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name != ""
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
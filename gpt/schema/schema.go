@@ -0,0 +1,68 @@
+// Package schema builds JSON schemas which satisfy OpenAI's strict structured
+// output rules, either generated from Go types or constructed by hand.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Schema is a JSON schema node. Keys are marshalled in the order they were
+// set, which matters as models generate properties in schema order
+type Schema struct {
+	keys   []string
+	values map[string]any
+}
+
+func newSchema() *Schema {
+	return &Schema{values: map[string]any{}}
+}
+
+// set sets a keyword on the schema, keeping its original position if it has
+// already been set
+func (s *Schema) set(key string, value any) *Schema {
+	if _, exists := s.values[key]; !exists {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+	return s
+}
+
+// get returns the value of a keyword
+func (s *Schema) get(key string) (any, bool) {
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// MarshalJSON encodes the schema with its keys in insertion order
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range s.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(s.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(valueJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// String returns the schema as JSON
+func (s *Schema) String() string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
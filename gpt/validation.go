@@ -3,104 +3,416 @@ package gpt
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 )
 
-// Validate the response schema that we provide to GPT for return data
+// SchemaLimits bounds the size of a schema. DefaultSchemaLimits matches the
+// limits OpenAI places on structured output schemas
+type SchemaLimits struct {
+	MaxDepth        int // Maximum nesting of objects and arrays
+	MaxProperties   int // Maximum object properties across the whole schema
+	MaxEnumValues   int // Maximum enum values across the whole schema
+	MaxStringLength int // Maximum total length of property names, definition names and enum values
+}
+
+var DefaultSchemaLimits = SchemaLimits{
+	MaxDepth:        10,
+	MaxProperties:   5000,
+	MaxEnumValues:   1000,
+	MaxStringLength: 120_000,
+}
+
+// SchemaError reports an invalid node of a schema by its JSON pointer path
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", pointer(e.Path), e.Message)
+}
+
+var validTypes = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+var validFormats = map[string]bool{
+	"date-time": true,
+	"time":      true,
+	"date":      true,
+	"duration":  true,
+	"email":     true,
+	"hostname":  true,
+	"ipv4":      true,
+	"ipv6":      true,
+	"uuid":      true,
+}
+
+// keywords which are valid JSON schema but rejected by OpenAI's strict mode
+var unsupportedKeywords = []string{
+	"allOf", "not", "oneOf", "if", "then", "else",
+	"dependentRequired", "dependentSchemas",
+	"patternProperties", "unevaluatedProperties", "propertyNames",
+	"minProperties", "maxProperties",
+	"unevaluatedItems", "contains", "minContains", "maxContains", "uniqueItems",
+	"prefixItems", "minLength", "maxLength",
+}
+
+// Validate the response schema that we provide to GPT for return data against
+// the subset of JSON schema supported by OpenAI's strict structured outputs
 func ValidateSchema(schema string) error {
+	return ValidateSchemaWithLimits(schema, DefaultSchemaLimits)
+}
+
+// ValidateSchemaWithLimits is ValidateSchema with custom size limits
+func ValidateSchemaWithLimits(schema string, limits SchemaLimits) error {
 	var obj any
 	err := json.Unmarshal([]byte(schema), &obj)
 	if err != nil {
 		return fmt.Errorf("schema is not valid json: %w", err)
 	}
-	return checkByType(obj.(map[string]any))
+	root, ok := obj.(map[string]any)
+	if !ok {
+		return &SchemaError{Message: "the schema must be a JSON object"}
+	}
+	if _, ok := root["anyOf"]; ok {
+		return &SchemaError{Message: "the root schema must not use anyOf"}
+	}
+	if root["type"] != "object" {
+		return &SchemaError{Message: "the root schema must have type 'object'"}
+	}
+
+	v := &validator{root: root, limits: limits}
+	if err := v.checkDefs(root); err != nil {
+		return err
+	}
+	return v.checkNode(root, "", 1)
 }
 
-func checkByType(obj map[string]any) error {
-	value, exists := obj["type"]
+type validator struct {
+	root         map[string]any
+	limits       SchemaLimits
+	properties   int
+	enumValues   int
+	stringLength int
+}
+
+func (v *validator) checkDefs(root map[string]any) error {
+	defs, exists := root["$defs"]
 	if !exists {
-		return checkNested(obj)
-	}
-	switch value {
-	case "object":
-		return checkObj(obj)
-	case "array":
-		return checkArr(obj)
-	case "string":
-		// TODO
 		return nil
-	default:
-		return fmt.Errorf("invalid value for key 'type' must be 'object' 'array' or 'string'")
 	}
+	defsMap, ok := defs.(map[string]any)
+	if !ok {
+		return &SchemaError{Path: "/$defs", Message: "$defs must be an object"}
+	}
+	for _, name := range sortedKeys(defsMap) {
+		path := "/$defs/" + escapePointer(name)
+		if err := v.countString(path, name); err != nil {
+			return err
+		}
+		if err := v.checkNode(defsMap[name], path, 1); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func checkNested(obj map[string]any) error {
-	excludedKeys := map[string]bool{
-		"additionalProperties": true,
-		"type":                 true,
-		"required":             true,
+func (v *validator) checkNode(node any, path string, depth int) error {
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("expected a schema object but got %T", node)}
+	}
+	if depth > v.limits.MaxDepth {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("schema exceeds the maximum nesting depth of %d", v.limits.MaxDepth)}
+	}
+	for _, keyword := range unsupportedKeywords {
+		if _, exists := obj[keyword]; exists {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("keyword '%s' is not supported", keyword)}
+		}
+	}
+
+	if ref, exists := obj["$ref"]; exists {
+		return v.checkRef(ref, path)
+	}
+	if anyOf, exists := obj["anyOf"]; exists {
+		return v.checkAnyOf(anyOf, path, depth)
+	}
+
+	types, err := typesOf(obj, path)
+	if err != nil {
+		return err
 	}
-	for key, value := range obj {
-		if excludedKeys[key] {
-			continue
+	if err := v.checkEnum(obj, types, path); err != nil {
+		return err
+	}
+	for _, t := range types {
+		var err error
+		switch t {
+		case "object":
+			err = v.checkObj(obj, path, depth)
+		case "array":
+			err = v.checkArr(obj, path, depth)
+		case "string":
+			err = checkString(obj, path)
+		case "number", "integer":
+			err = checkNumber(obj, path)
 		}
-		if nestedMap, ok := value.(map[string]any); ok {
-			return checkByType(nestedMap)
-		} else {
-			return fmt.Errorf("expected value for key %s to be map[string]any, but got %T", key, value)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func checkObj(obj map[string]any) error {
+// typesOf returns the types of a schema, which may be a single type or a
+// list such as ["string", "null"] for nullable values
+func typesOf(obj map[string]any, path string) ([]string, error) {
+	value, exists := obj["type"]
+	if !exists {
+		if _, hasEnum := obj["enum"]; hasEnum {
+			return nil, nil
+		}
+		if _, hasConst := obj["const"]; hasConst {
+			return nil, nil
+		}
+		return nil, &SchemaError{Path: path, Message: "a schema must contain the 'type' field, '$ref' or 'anyOf'"}
+	}
+
+	var types []string
+	switch t := value.(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				return nil, &SchemaError{Path: path + "/type", Message: "type must be a string or a list of strings"}
+			}
+			types = append(types, s)
+		}
+	default:
+		return nil, &SchemaError{Path: path + "/type", Message: "type must be a string or a list of strings"}
+	}
+	if len(types) == 0 {
+		return nil, &SchemaError{Path: path + "/type", Message: "type must not be empty"}
+	}
+	for _, t := range types {
+		if !validTypes[t] {
+			return nil, &SchemaError{
+				Path:    path + "/type",
+				Message: fmt.Sprintf("invalid value '%s' for key 'type' must be one of object, array, string, number, integer, boolean or null", t),
+			}
+		}
+	}
+	return types, nil
+}
+
+func (v *validator) checkObj(obj map[string]any, path string, depth int) error {
 	adProp, apExists := obj["additionalProperties"]
 	if !apExists || adProp != false {
-		return fmt.Errorf("an object must contain the additionalProperties field and it must be false")
+		return &SchemaError{Path: path, Message: "an object must contain the additionalProperties field and it must be false"}
 	}
 	props, pExists := obj["properties"]
 	if !pExists {
-		return fmt.Errorf("an object must contain the 'properties' field")
+		return &SchemaError{Path: path, Message: "an object must contain the 'properties' field"}
 	}
 	propsMap, ok := props.(map[string]any)
 	if !ok {
-		return fmt.Errorf("properties must be a valid object")
-	}
-	propKeys := make([]string, 0, len(propsMap))
-	for key := range propsMap {
-		propKeys = append(propKeys, key)
+		return &SchemaError{Path: path + "/properties", Message: "properties must be a valid object"}
 	}
+	propKeys := sortedKeys(propsMap)
+
+	// Verify all properties are required
 	required, rExists := obj["required"]
 	if !rExists && len(propKeys) > 0 {
-		return fmt.Errorf("each value in properties must be in the required array")
+		return &SchemaError{Path: path, Message: "each value in properties must be in the required array"}
 	}
-	// Verify all properties are required
 	if rExists {
 		requiredList, ok := required.([]any)
 		if !ok {
-			return fmt.Errorf("required must be an array")
-		}
-		if len(requiredList) != len(propKeys) {
-			return fmt.Errorf("each value in properties must be in the required array")
+			return &SchemaError{Path: path + "/required", Message: "required must be an array"}
 		}
 		requiredSet := make(map[string]bool)
 		for _, r := range requiredList {
-			if rStr, ok := r.(string); ok {
-				requiredSet[rStr] = true
+			rStr, ok := r.(string)
+			if !ok {
+				return &SchemaError{Path: path + "/required", Message: "required must only contain strings"}
 			}
+			if _, defined := propsMap[rStr]; !defined {
+				return &SchemaError{Path: path + "/required", Message: fmt.Sprintf("required property %s is not defined in properties", rStr)}
+			}
+			requiredSet[rStr] = true
 		}
 		for _, key := range propKeys {
 			if !requiredSet[key] {
-				return fmt.Errorf("property %s must be required", key)
+				return &SchemaError{
+					Path:    path,
+					Message: fmt.Sprintf("each value in properties must be in the required array, property %s is not required", key),
+				}
 			}
 		}
 	}
-	return checkNested(obj)
+
+	v.properties += len(propKeys)
+	if v.properties > v.limits.MaxProperties {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("schema exceeds the maximum of %d properties", v.limits.MaxProperties)}
+	}
+	for _, key := range propKeys {
+		propPath := path + "/properties/" + escapePointer(key)
+		if err := v.countString(propPath, key); err != nil {
+			return err
+		}
+		if err := v.checkNode(propsMap[key], propPath, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func checkArr(obj map[string]any) error {
-	_, exists := obj["items"]
+func (v *validator) checkArr(obj map[string]any, path string, depth int) error {
+	items, exists := obj["items"]
 	if !exists {
-		return fmt.Errorf("an array object must contain the key 'items'")
+		return &SchemaError{Path: path, Message: "an array object must contain the key 'items'"}
+	}
+	for _, key := range []string{"minItems", "maxItems"} {
+		if value, exists := obj[key]; exists && !isNonNegativeInteger(value) {
+			return &SchemaError{Path: path + "/" + key, Message: key + " must be a non negative integer"}
+		}
+	}
+	return v.checkNode(items, path+"/items", depth+1)
+}
+
+func checkString(obj map[string]any, path string) error {
+	if pattern, exists := obj["pattern"]; exists {
+		if _, ok := pattern.(string); !ok {
+			return &SchemaError{Path: path + "/pattern", Message: "pattern must be a string"}
+		}
+	}
+	if format, exists := obj["format"]; exists {
+		formatStr, _ := format.(string)
+		if !validFormats[formatStr] {
+			return &SchemaError{Path: path + "/format", Message: fmt.Sprintf("unsupported format %v", format)}
+		}
+	}
+	return nil
+}
+
+func checkNumber(obj map[string]any, path string) error {
+	for _, key := range []string{"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf"} {
+		if value, exists := obj[key]; exists {
+			if _, ok := value.(float64); !ok {
+				return &SchemaError{Path: path + "/" + key, Message: key + " must be a number"}
+			}
+		}
+	}
+	return nil
+}
+
+// checkEnum checks enum and const values are primitives of the schema's types
+func (v *validator) checkEnum(obj map[string]any, types []string, path string) error {
+	values := []any{}
+	if enum, exists := obj["enum"]; exists {
+		enumList, ok := enum.([]any)
+		if !ok || len(enumList) == 0 {
+			return &SchemaError{Path: path + "/enum", Message: "enum must be a non empty array"}
+		}
+		values = enumList
+		v.enumValues += len(enumList)
+		if v.enumValues > v.limits.MaxEnumValues {
+			return &SchemaError{Path: path + "/enum", Message: fmt.Sprintf("schema exceeds the maximum of %d enum values", v.limits.MaxEnumValues)}
+		}
+	}
+	if value, exists := obj["const"]; exists {
+		values = append(values, value)
+	}
+
+	for i, value := range values {
+		valuePath := fmt.Sprintf("%s/enum/%d", path, i)
+		valueType := jsonType(value)
+		if valueType == "object" || valueType == "array" {
+			return &SchemaError{Path: valuePath, Message: "enum values must be strings, numbers, booleans or null"}
+		}
+		if len(types) > 0 && !typeAllowed(types, valueType) {
+			return &SchemaError{Path: valuePath, Message: fmt.Sprintf("enum value %v does not match type %s", value, strings.Join(types, " or "))}
+		}
+		if s, ok := value.(string); ok {
+			if err := v.countString(valuePath, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) checkAnyOf(anyOf any, path string, depth int) error {
+	options, ok := anyOf.([]any)
+	if !ok || len(options) == 0 {
+		return &SchemaError{Path: path + "/anyOf", Message: "anyOf must be a non empty array"}
+	}
+	for i, option := range options {
+		if err := v.checkNode(option, fmt.Sprintf("%s/anyOf/%d", path, i), depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRef checks that a reference points to the root or a definition. The
+// target itself is validated on its own so recursive references terminate
+func (v *validator) checkRef(ref any, path string) error {
+	refStr, ok := ref.(string)
+	if !ok {
+		return &SchemaError{Path: path + "/$ref", Message: "$ref must be a string"}
+	}
+	if refStr == "#" {
+		return nil
+	}
+	name, found := strings.CutPrefix(refStr, "#/$defs/")
+	if !found {
+		return &SchemaError{Path: path + "/$ref", Message: fmt.Sprintf("$ref %s must point to the root or a definition in $defs", refStr)}
+	}
+	defs, _ := v.root["$defs"].(map[string]any)
+	if _, exists := defs[unescapePointer(name)]; !exists {
+		return &SchemaError{Path: path + "/$ref", Message: fmt.Sprintf("$ref %s does not match any definition", refStr)}
+	}
+	return nil
+}
+
+func (v *validator) countString(path, s string) error {
+	v.stringLength += len(s)
+	if v.stringLength > v.limits.MaxStringLength {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("schema exceeds the maximum total string length of %d", v.limits.MaxStringLength)}
+	}
+	return nil
+}
+
+func typeAllowed(types []string, valueType string) bool {
+	for _, t := range types {
+		if t == valueType || (t == "number" && valueType == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func isNonNegativeInteger(value any) bool {
+	f, ok := value.(float64)
+	return ok && f >= 0 && f == float64(int64(f))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
-	return checkNested(obj)
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,32 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sentinel errors describing why an extraction produced no usable result, for
+// use with errors.Is
+var (
+	ErrRefusal        = errors.New("model refused to respond")
+	ErrTruncated      = errors.New("response was truncated")
+	ErrInvalidJSON    = errors.New("invalid JSON response")
+	ErrSchemaMismatch = errors.New("response does not match schema")
+)
+
+// APIError is returned by providers when the API responds with an error
+// status. Use errors.As to inspect it
+type APIError struct {
+	Provider   string // Name of the API, eg GPT or Anthropic
+	StatusCode int
+	Code       string        // Error code, eg insufficient_quota
+	Type       string        // Error type, eg rate_limit_error
+	Message    string        // Human readable message from the error body
+	Body       string        // Raw response body
+	RetryAfter time.Duration // Delay requested by the server, if any
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API request failed with status code: %d and message %s", e.Provider, e.StatusCode, e.Body)
+}
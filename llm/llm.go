@@ -0,0 +1,57 @@
+// Package llm defines the interface between scrapeai and the language model
+// backends that perform the actual extraction.
+package llm
+
+import "context"
+
+// Provider is implemented by any backend capable of extracting structured
+// data from a page. The gpt package provides the default OpenAI implementation.
+type Provider interface {
+	Extract(ctx context.Context, req *Request) (*Response, error)
+}
+
+// Request is the input for a single extraction call
+type Request struct {
+	Prompt    string  // Instructions describing what to extract
+	Page      string  // Page content to extract from
+	Schema    string  // JSON schema the result must conform to
+	Repair    *Repair // Set when asking the model to correct a previous response
+	MaxTokens int     // Limit on output tokens, zero for the provider's default
+}
+
+// Repair describes a previous response which failed validation so that the
+// model can be asked to correct it
+type Repair struct {
+	Response string // The rejected response
+	Problem  string // Why the response was rejected
+}
+
+// RepairInstructions is the message sent to the model after a rejected
+// response, for use by providers
+func RepairInstructions(problem string) string {
+	return "The JSON you returned was rejected: " + problem +
+		"\nRespond again with corrected JSON which matches the schema exactly."
+}
+
+// Response is the output of a single extraction call
+type Response struct {
+	Content      string // JSON conforming to the requested schema
+	Usage        Usage
+	Model        string // Model which served the request as reported by the API
+	ID           string // Response ID assigned by the API
+	FinishReason string // Reason the model stopped generating, eg stop or length
+	Refusal      string // Explanation given by the model when it declines to respond
+}
+
+// Truncated reports whether the model ran out of output tokens, in which case
+// the content is likely to be incomplete
+func (r *Response) Truncated() bool {
+	return r.FinishReason == "length" || r.FinishReason == "max_tokens"
+}
+
+// Usage reports the tokens consumed by a single extraction call
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes the limits of a model
+type ModelInfo struct {
+	Name            string
+	ContextWindow   int    // Maximum tokens of input and output combined
+	MaxOutputTokens int    // Maximum tokens the model can generate
+	Encoding        string // Name of the tokenizer encoding, see the tokenizer package
+}
+
+// ModelNamer is implemented by providers which can report the model they use
+// so that requests can be sized to the model's limits
+type ModelNamer interface {
+	Model() string
+}
+
+var (
+	modelsMu sync.RWMutex
+	// Anthropic does not publish its tokenizer so cl100k_base is used as an
+	// approximation for Claude models
+	models = map[string]ModelInfo{
+		"gpt-4o-mini":       {Name: "gpt-4o-mini", ContextWindow: 128_000, MaxOutputTokens: 16_384, Encoding: "o200k_base"},
+		"gpt-4o":            {Name: "gpt-4o", ContextWindow: 128_000, MaxOutputTokens: 16_384, Encoding: "o200k_base"},
+		"gpt-4.1":           {Name: "gpt-4.1", ContextWindow: 1_047_576, MaxOutputTokens: 32_768, Encoding: "o200k_base"},
+		"gpt-4.1-mini":      {Name: "gpt-4.1-mini", ContextWindow: 1_047_576, MaxOutputTokens: 32_768, Encoding: "o200k_base"},
+		"gpt-4.1-nano":      {Name: "gpt-4.1-nano", ContextWindow: 1_047_576, MaxOutputTokens: 32_768, Encoding: "o200k_base"},
+		"o1":                {Name: "o1", ContextWindow: 200_000, MaxOutputTokens: 100_000, Encoding: "o200k_base"},
+		"o3-mini":           {Name: "o3-mini", ContextWindow: 200_000, MaxOutputTokens: 100_000, Encoding: "o200k_base"},
+		"gpt-4-turbo":       {Name: "gpt-4-turbo", ContextWindow: 128_000, MaxOutputTokens: 4_096, Encoding: "cl100k_base"},
+		"gpt-4":             {Name: "gpt-4", ContextWindow: 8_192, MaxOutputTokens: 8_192, Encoding: "cl100k_base"},
+		"gpt-3.5-turbo":     {Name: "gpt-3.5-turbo", ContextWindow: 16_385, MaxOutputTokens: 4_096, Encoding: "cl100k_base"},
+		"claude-haiku-4-5":  {Name: "claude-haiku-4-5", ContextWindow: 200_000, MaxOutputTokens: 64_000, Encoding: "cl100k_base"},
+		"claude-sonnet-4-5": {Name: "claude-sonnet-4-5", ContextWindow: 200_000, MaxOutputTokens: 64_000, Encoding: "cl100k_base"},
+		"claude-opus-4-1":   {Name: "claude-opus-4-1", ContextWindow: 200_000, MaxOutputTokens: 32_000, Encoding: "cl100k_base"},
+	}
+)
+
+// RegisterModel adds or replaces a model in the registry, eg for local models
+// or models released after this version
+func RegisterModel(info ModelInfo) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	models[info.Name] = info
+}
+
+// LookupModel returns the registered model with the given name. Dated
+// snapshots such as gpt-4o-mini-2024-07-18 match the longest registered
+// name they start with
+func LookupModel(name string) (ModelInfo, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	if info, ok := models[name]; ok {
+		return info, true
+	}
+	var best ModelInfo
+	found := false
+	for key, info := range models {
+		if strings.HasPrefix(name, key+"-") && len(key) > len(best.Name) {
+			best, found = info, true
+		}
+	}
+	return best, found
+}
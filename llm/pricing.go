@@ -0,0 +1,57 @@
+package llm
+
+import "strings"
+
+// Price is the cost of a model in US dollars per million tokens
+type Price struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// PriceTable maps model names to their prices
+type PriceTable map[string]Price
+
+// DefaultPrices are the published list prices at the time of writing. Prices
+// change, so callers who rely on the estimates should supply their own table
+var DefaultPrices = PriceTable{
+	"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4.1":           {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	"gpt-4.1-mini":      {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1-nano":      {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"o1":                {InputPerMillion: 15.00, OutputPerMillion: 60.00},
+	"o3-mini":           {InputPerMillion: 1.10, OutputPerMillion: 4.40},
+	"gpt-4-turbo":       {InputPerMillion: 10.00, OutputPerMillion: 30.00},
+	"gpt-4":             {InputPerMillion: 30.00, OutputPerMillion: 60.00},
+	"gpt-3.5-turbo":     {InputPerMillion: 0.50, OutputPerMillion: 1.50},
+	"claude-haiku-4-5":  {InputPerMillion: 1.00, OutputPerMillion: 5.00},
+	"claude-sonnet-4-5": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-opus-4-1":   {InputPerMillion: 15.00, OutputPerMillion: 75.00},
+}
+
+// Lookup returns the price of the model. As with LookupModel, dated
+// snapshots match the longest model name they start with
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	var best Price
+	bestLen := 0
+	for key, price := range t {
+		if strings.HasPrefix(model, key+"-") && len(key) > bestLen {
+			best, bestLen = price, len(key)
+		}
+	}
+	return best, bestLen > 0
+}
+
+// Cost returns the estimated cost in US dollars of the usage on the model and
+// whether the model has a price in the table
+func (t PriceTable) Cost(model string, usage Usage) (float64, bool) {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.InputPerMillion +
+		float64(usage.CompletionTokens)*price.OutputPerMillion) / 1_000_000, true
+}
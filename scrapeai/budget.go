@@ -0,0 +1,280 @@
+package scrapeai
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+// ErrBudgetExceeded is returned when a call to the provider would exceed one
+// of the limits of the request's Budget
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetPolicy controls what happens when a call would exceed the budget
+type BudgetPolicy int
+
+const (
+	// Refuse the call and return ErrBudgetExceeded
+	BudgetRefuse BudgetPolicy = iota
+	// Send as much of the page as the budget allows and skip the rest. If
+	// nothing fits ErrBudgetExceeded is returned
+	BudgetTruncate
+)
+
+// Budget limits the tokens and estimated cost spent by scrapes. A single
+// Budget can be shared by many concurrent Scrape calls, in which case the
+// cumulative limits apply to all of them together. Zero limits are unlimited.
+//
+// Limits are checked before each call to the provider, including repairs and
+// retries of truncated responses, using the tokens in the prompt and page.
+// The output of each call is capped to what is left of the budget, and the
+// actual usage is recorded once the call returns.
+// Cost limits are only enforced for models with a price in the price table
+type Budget struct {
+	MaxTokens        int     // Cumulative token limit across all scrapes
+	MaxCost          float64 // Cumulative cost limit in US dollars
+	PerRequestTokens int     // Token limit for a single scrape
+	PerRequestCost   float64 // Cost limit in US dollars for a single scrape
+	OnExceeded       BudgetPolicy
+
+	mu     sync.Mutex
+	tokens int
+	cost   float64
+}
+
+// Spent returns the tokens and estimated cost recorded against the budget,
+// including calls which are still in flight
+func (b *Budget) Spent() (int, float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens, b.cost
+}
+
+// acquire reserves room in the budget for a call of the given number of input
+// tokens made by a scrape which has used the given usage so far. It returns
+// the number of tokens reserved, which is less than requested if the call was
+// shrunk to fit under BudgetTruncate
+func (b *Budget) acquire(tokens int, price llm.Price, priced bool, used *Usage) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	allowed := tokens
+	limit := func(remaining int) {
+		allowed = min(allowed, remaining)
+	}
+	if b.MaxTokens > 0 {
+		limit(b.MaxTokens - b.tokens)
+	}
+	if b.PerRequestTokens > 0 {
+		limit(b.PerRequestTokens - used.TotalTokens)
+	}
+	if priced && price.InputPerMillion > 0 {
+		if b.MaxCost > 0 {
+			limit(int((b.MaxCost - b.cost) / price.InputPerMillion * 1_000_000))
+		}
+		if b.PerRequestCost > 0 {
+			limit(int((b.PerRequestCost - used.EstimatedCost) / price.InputPerMillion * 1_000_000))
+		}
+	}
+
+	if allowed < tokens && (b.OnExceeded == BudgetRefuse || allowed <= 0) {
+		return 0, fmt.Errorf("%w: call of %d tokens does not fit in the remaining budget", ErrBudgetExceeded, tokens)
+	}
+	b.tokens += allowed
+	b.cost += float64(allowed) * price.InputPerMillion / 1_000_000
+	return allowed, nil
+}
+
+// settle replaces a reservation with the actual usage of the calls made
+func (b *Budget) settle(reservedTokens int, reservedCost float64, calls []CallUsage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens -= reservedTokens
+	b.cost -= reservedCost
+	for _, call := range calls {
+		b.tokens += call.Usage.TotalTokens
+		b.cost += call.EstimatedCost
+	}
+}
+
+// reservation is room held in a budget for a call to the provider in flight,
+// covering its input and the most output it may produce. A nil reservation is
+// valid and does nothing
+type reservation struct {
+	budget *Budget
+	price  llm.Price
+	priced bool
+	tokens int
+	cost   float64
+}
+
+// settle records the actual usage of the calls made with the reservation in
+// place of the room held
+func (r *reservation) settle(calls []CallUsage) {
+	if r == nil {
+		return
+	}
+	r.budget.settle(r.tokens, r.cost, calls)
+}
+
+// shrink reduces the reservation to the given number of input tokens
+func (r *reservation) shrink(tokens int) {
+	if tokens >= r.tokens {
+		return
+	}
+	cost := float64(tokens) * r.price.InputPerMillion / 1_000_000
+	r.budget.settle(r.tokens-tokens, r.cost-cost, nil)
+	r.tokens, r.cost = tokens, cost
+}
+
+// limitOutput reserves room for the response of the call and returns the most
+// output tokens it may use, reporting whether the budget lowered the limit.
+// want is the limit the call would otherwise have, zero for the provider's
+// default, and ceiling is the most output the model can produce, zero if it
+// is not known
+func (r *reservation) limitOutput(want int, ceiling int, used *Usage) (int, bool, error) {
+	if r == nil {
+		return want, false, nil
+	}
+	b := r.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	left, limited := 0, false
+	limit := func(remaining int) {
+		if !limited || remaining < left {
+			left, limited = remaining, true
+		}
+	}
+	if b.MaxTokens > 0 {
+		limit(b.MaxTokens - b.tokens)
+	}
+	if b.PerRequestTokens > 0 {
+		limit(b.PerRequestTokens - used.TotalTokens - r.tokens)
+	}
+	if r.priced && r.price.OutputPerMillion > 0 {
+		if b.MaxCost > 0 {
+			limit(int((b.MaxCost - b.cost) / r.price.OutputPerMillion * 1_000_000))
+		}
+		if b.PerRequestCost > 0 {
+			limit(int((b.PerRequestCost - used.EstimatedCost - r.cost) / r.price.OutputPerMillion * 1_000_000))
+		}
+	}
+	if !limited {
+		return want, false, nil
+	}
+	if left <= 0 {
+		return 0, false, fmt.Errorf("%w: no tokens are left for the response", ErrBudgetExceeded)
+	}
+	if want > 0 {
+		ceiling = want
+	}
+	capped := ceiling == 0 || left < ceiling
+	if !capped {
+		left = ceiling
+	}
+
+	cost := float64(left) * r.price.OutputPerMillion / 1_000_000
+	b.tokens += left
+	b.cost += cost
+	r.tokens += left
+	r.cost += cost
+	if !capped {
+		return want, false, nil
+	}
+	return left, true, nil
+}
+
+// reserve reserves room in the request's budget for a call with the given
+// number of input tokens. The reservation may hold fewer tokens than
+// requested under BudgetTruncate
+func reserve(req *ScrapeAiRequest, tokens int, model string, used *Usage) (*reservation, error) {
+	// providers which do not report their model up front are priced by the
+	// model reported in their previous responses
+	if model == "" {
+		model = used.Model()
+	}
+	price, priced := req.Prices.Lookup(model)
+	allowed, err := req.Budget.acquire(tokens, price, priced, used)
+	if err != nil {
+		return nil, err
+	}
+	return &reservation{
+		budget: req.Budget,
+		price:  price,
+		priced: priced,
+		tokens: allowed,
+		cost:   float64(allowed) * price.InputPerMillion / 1_000_000,
+	}, nil
+}
+
+// reserveCall reserves room in the request's budget for a repair or a retry
+// of a truncated response. These cannot be cut down to fit so are refused
+// when the budget is short whatever its policy
+func reserveCall(req *ScrapeAiRequest, tokens int, model string, used *Usage) (*reservation, error) {
+	if req.Budget == nil {
+		return nil, nil
+	}
+	hold, err := reserve(req, tokens, model, used)
+	if err != nil {
+		return nil, err
+	}
+	if hold.tokens < tokens {
+		hold.settle(nil)
+		return nil, fmt.Errorf("%w: call of %d tokens does not fit in the remaining budget", ErrBudgetExceeded, tokens)
+	}
+	return hold, nil
+}
+
+// reserveBudget reserves room in the request's budget for sending the chunk
+// to the provider. Under BudgetTruncate the chunk is cut down to fit the
+// remaining budget, in which case the returned chunk differs from the input
+func reserveBudget(
+	req *ScrapeAiRequest,
+	chunk string,
+	model string,
+	count scraping.TokenCounter,
+	used *Usage,
+) (string, *reservation, error) {
+	if req.Budget == nil {
+		return chunk, nil, nil
+	}
+
+	tokens := count(req.Prompt + "\n\n" + req.ContentFormat.render(chunk))
+	hold, err := reserve(req, tokens, model, used)
+	if err != nil {
+		return "", nil, err
+	}
+	if hold.tokens == tokens {
+		return chunk, hold, nil
+	}
+
+	// half of what is left is kept for the response
+	truncated := ""
+	if pageBudget := hold.tokens/2 - count(req.Prompt); pageBudget > 0 {
+		truncated, err = truncateHTML(chunk, pageBudget, req.ContentFormat.counter(count))
+	}
+	if err != nil || truncated == "" {
+		hold.settle(nil)
+		return "", nil, fmt.Errorf("%w: the prompt does not fit in the remaining budget", ErrBudgetExceeded)
+	}
+	hold.shrink(count(req.Prompt + "\n\n" + req.ContentFormat.render(truncated)))
+	return truncated, hold, nil
+}
+
+// truncateHTML returns as much of the start of the HTML as fits in maxTokens,
+// split along DOM boundaries
+func truncateHTML(html string, maxTokens int, count scraping.TokenCounter) (string, error) {
+	doc, err := scraping.GoQueryDocFromBody(html)
+	if err != nil {
+		return "", err
+	}
+	chunks := scraping.ChunkDocument(doc, maxTokens, count)
+	if len(chunks) == 0 {
+		return "", nil
+	}
+	return chunks[0], nil
+}
@@ -0,0 +1,46 @@
+package scrapeai
+
+import (
+	"fmt"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+// Errors returned by Scrape can be inspected with errors.Is and errors.As
+// rather than matching on their messages, eg
+//
+//	var apiErr *scrapeai.APIError
+//	if errors.As(err, &apiErr) && apiErr.StatusCode == 429 { ... }
+var (
+	// ErrRefusal is returned when the model declines to extract the data
+	ErrRefusal = llm.ErrRefusal
+	// ErrTruncated is returned when the model runs out of output tokens
+	ErrTruncated = llm.ErrTruncated
+	// ErrInvalidJSON is returned when the response is not valid JSON
+	ErrInvalidJSON = llm.ErrInvalidJSON
+	// ErrSchemaMismatch is returned when the response does not match the schema
+	ErrSchemaMismatch = llm.ErrSchemaMismatch
+	// ErrNoMatch is returned when none of the request's selectors match
+	ErrNoMatch = scraping.ErrNoMatch
+)
+
+// APIError is returned when the provider's API responds with an error status
+type APIError = llm.APIError
+
+// FetchError is returned when the page cannot be fetched
+type FetchError = scraping.FetchError
+
+// RefusalError is returned when the model declines to extract data from every
+// part of the page. It matches ErrRefusal with errors.Is
+type RefusalError struct {
+	Message string // Explanation given by the model
+}
+
+func (e *RefusalError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRefusal, e.Message)
+}
+
+func (e *RefusalError) Is(target error) bool {
+	return target == ErrRefusal
+}
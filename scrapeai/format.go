@@ -0,0 +1,46 @@
+package scrapeai
+
+import "github.com/samredway/scrapeai/scraping"
+
+// ContentFormat controls the form the page is sent to the provider in
+type ContentFormat int
+
+const (
+	// Send the stripped HTML of the page, the default
+	FormatHTML ContentFormat = iota
+	// Convert the page to Markdown, which keeps headings, lists, tables and
+	// links in far fewer tokens than HTML
+	FormatMarkdown
+	// Convert the page to plain text
+	FormatText
+)
+
+// render converts HTML to the format, falling back to the HTML if it cannot
+// be converted
+func (f ContentFormat) render(html string) string {
+	var content string
+	var err error
+	switch f {
+	case FormatMarkdown:
+		content, err = scraping.HTMLToMarkdown(html)
+	case FormatText:
+		content, err = scraping.HTMLToText(html)
+	default:
+		return html
+	}
+	if err != nil {
+		return html
+	}
+	return content
+}
+
+// counter returns a TokenCounter which measures HTML by the tokens it takes
+// once converted to the format, so that pages are chunked by what is sent
+func (f ContentFormat) counter(count scraping.TokenCounter) scraping.TokenCounter {
+	if f == FormatHTML {
+		return count
+	}
+	return func(html string) int {
+		return count(f.render(html))
+	}
+}
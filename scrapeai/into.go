@@ -0,0 +1,38 @@
+package scrapeai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/samredway/scrapeai/gpt/schema"
+)
+
+// ScrapeInto scrapes the url and decodes the results into a T, which must be
+// a struct. The response schema is generated from T so there is no need to
+// write one by hand, see schema.FromType for the supported types and tags.
+// Any schema set with WithSchema is replaced
+func ScrapeInto[T any](ctx context.Context, url string, prompt string, options ...Option) (T, *ScrapeAiResult, error) {
+	var out T
+
+	s, err := schema.For[T]()
+	if err != nil {
+		return out, nil, fmt.Errorf("generating schema: %w", err)
+	}
+
+	options = append(options, WithJSONSchema(s))
+	req, err := NewScrapeAiRequest(url, prompt, options...)
+	if err != nil {
+		return out, nil, err
+	}
+
+	result, err := Scrape(ctx, req)
+	if err != nil {
+		return out, nil, err
+	}
+
+	if err := json.Unmarshal([]byte(result.Results), &out); err != nil {
+		return out, result, fmt.Errorf("decoding results: %w", err)
+	}
+	return out, result, nil
+}
@@ -0,0 +1,205 @@
+package scrapeai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// mergeResults combines the JSON results extracted from each chunk of a page
+// into a single result shaped by the schema. Arrays are concatenated with
+// duplicate items removed, objects are merged property by property and
+// scalars resolve to the most common non empty value
+func mergeResults(schema string, results []string) (string, error) {
+	if len(results) == 1 {
+		return results[0], nil
+	}
+
+	var schemaObj map[string]any
+	if err := json.Unmarshal([]byte(schema), &schemaObj); err != nil {
+		return "", fmt.Errorf("schema is not valid json: %w", err)
+	}
+
+	values := make([]any, 0, len(results))
+	for _, result := range results {
+		var value any
+		if err := json.Unmarshal([]byte(result), &value); err != nil {
+			return "", fmt.Errorf("invalid JSON response: %w", err)
+		}
+		values = append(values, value)
+	}
+
+	m := merger{root: schemaObj}
+	merged, err := json.Marshal(m.merge(schemaObj, values))
+	if err != nil {
+		return "", fmt.Errorf("marshaling merged result: %w", err)
+	}
+	return string(merged), nil
+}
+
+type merger struct {
+	root map[string]any
+}
+
+func (m merger) merge(schema map[string]any, values []any) any {
+	schema = m.resolve(schema)
+
+	switch kindOf(schema, values) {
+	case "object":
+		return m.mergeObjects(schema, values)
+	case "array":
+		return mergeArrays(values)
+	default:
+		return mergeScalars(values)
+	}
+}
+
+func (m merger) mergeObjects(schema map[string]any, values []any) any {
+	props, _ := schema["properties"].(map[string]any)
+	byKey := map[string][]any{}
+	keys := []string{}
+	for _, value := range values {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, v := range obj {
+			if _, seen := byKey[key]; !seen {
+				keys = append(keys, key)
+			}
+			byKey[key] = append(byKey[key], v)
+		}
+	}
+
+	merged := make(map[string]any, len(keys))
+	for _, key := range keys {
+		propSchema, _ := props[key].(map[string]any)
+		merged[key] = m.merge(propSchema, byKey[key])
+	}
+	return merged
+}
+
+// mergeArrays concatenates arrays preserving order and dropping duplicates
+func mergeArrays(values []any) any {
+	merged := []any{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		items, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			key := canonical(item)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// mergeScalars picks the most frequent non empty value, preferring the value
+// seen first on ties. If every value is empty the first value is returned
+func mergeScalars(values []any) any {
+	counts := map[string]int{}
+	var best any
+	bestCount := 0
+	for _, value := range values {
+		if isEmpty(value) {
+			continue
+		}
+		key := canonical(value)
+		counts[key]++
+		if counts[key] > bestCount {
+			best = value
+			bestCount = counts[key]
+		}
+	}
+	if bestCount == 0 && len(values) > 0 {
+		return values[0]
+	}
+	return best
+}
+
+// resolve follows local $ref pointers and picks the first non null option of
+// an anyOf so that nullable fields merge according to their underlying type
+func (m merger) resolve(schema map[string]any) map[string]any {
+	for schema != nil {
+		if ref, ok := schema["$ref"].(string); ok {
+			schema = m.lookup(ref)
+			continue
+		}
+		if anyOf, ok := schema["anyOf"].([]any); ok {
+			var next map[string]any
+			for _, option := range anyOf {
+				if optionMap, ok := option.(map[string]any); ok && optionMap["type"] != "null" {
+					next = optionMap
+					break
+				}
+			}
+			schema = next
+			continue
+		}
+		break
+	}
+	return schema
+}
+
+func (m merger) lookup(ref string) map[string]any {
+	var node any = m.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		nodeMap, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = nodeMap[part]
+	}
+	resolved, _ := node.(map[string]any)
+	return resolved
+}
+
+// kindOf returns the kind of merge to perform from the schema type, falling
+// back to the shape of the values when the schema does not say
+func kindOf(schema map[string]any, values []any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, option := range t {
+			if option == "object" || option == "array" {
+				return option.(string)
+			}
+		}
+	}
+	for _, value := range values {
+		switch value.(type) {
+		case map[string]any:
+			return "object"
+		case []any:
+			return "array"
+		}
+	}
+	return "scalar"
+}
+
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	}
+	return false
+}
+
+// canonical returns a stable JSON encoding used to compare values. Maps are
+// encoded with sorted keys so equal objects always compare equal
+func canonical(value any) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
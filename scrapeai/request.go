@@ -2,29 +2,64 @@ package scrapeai
 
 import (
 	"context"
+	"slices"
 
 	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/gpt/schema"
+	"github.com/samredway/scrapeai/llm"
 	"github.com/samredway/scrapeai/scraping"
 )
 
 // functional options for ScrapeAiRequest
 type Option func(*ScrapeAiRequest)
 
-// FetchFunc is a function type for fetching a web page
-// See scraping/utils/FetchFromChromedp for the default implementation
+// FetchFunc is a function type for fetching a web page, eg
+// scraping.FetchFromChromedp. When none is given pages are fetched with
+// scraping.FetchChromedp
 type FetchFunc func(ctx context.Context, url string) (string, error)
 
-// default fetch function
-var defaultFetchFunc = scraping.FetchFromChromedp
+// Fetcher fetches a web page along with the metadata of the response
+type Fetcher = scraping.Fetcher
+
+// FetchResult is a fetched page along with the metadata of the response
+type FetchResult = scraping.FetchResult
+
+// default number of attempts to correct an invalid response
+const defaultRepairAttempts = 1
+
+// default number of times a truncated response is retried with a larger
+// output token limit
+const defaultTruncationRetries = 2
+
+// default fetcher
+var defaultFetcher Fetcher = scraping.FetcherFunc(scraping.FetchChromedp)
 
 // Allows specifying a fetch function for collecting the web page the default
-// is scraping.FetchFromChromedp
+// is scraping.FetchChromedp. Only the body of the page is available from
+// a FetchFunc, use WithFetcher to report the status, headers and final URL
 func WithFetchFunc(f FetchFunc) Option {
 	return func(r *ScrapeAiRequest) {
 		r.FetchFunc = f
 	}
 }
 
+// Allows specifying the Fetcher used to collect the web page, eg
+// scraping.FetcherFunc(scraping.FetchHTTP). It takes precedence over
+// WithFetchFunc. The default renders the page with chromedp
+func WithFetcher(f Fetcher) Option {
+	return func(r *ScrapeAiRequest) {
+		r.Fetcher = f
+	}
+}
+
+// Allows specifying the LLM backend used to extract data from the page. The
+// default is the OpenAI provider from the gpt package
+func WithProvider(p llm.Provider) Option {
+	return func(r *ScrapeAiRequest) {
+		r.Provider = p
+	}
+}
+
 // Allows specifying a response schema for the return data. The default is a
 // list of strings
 func WithSchema(s string) Option {
@@ -33,23 +68,173 @@ func WithSchema(s string) Option {
 	}
 }
 
+// Allows specifying the response schema with one built by the schema package,
+// for example schema.Object().Prop("title", schema.String())
+func WithJSONSchema(s *schema.Schema) Option {
+	return WithSchema(s.String())
+}
+
+// Allows specifying the form the page is sent to the provider in. The default
+// is FormatHTML, FormatMarkdown and FormatText take far fewer tokens
+func WithContentFormat(f ContentFormat) Option {
+	return func(r *ScrapeAiRequest) {
+		r.ContentFormat = f
+	}
+}
+
+// Allows narrowing the page to the elements matching any of the selectors
+// before it is sent to the provider. Selectors are CSS, eg "#product-grid",
+// or XPath if they start with "/" or "(", eg "//table[@class='prices']"
+func WithSelector(selectors ...string) Option {
+	return func(r *ScrapeAiRequest) {
+		r.Selectors = append(r.Selectors, selectors...)
+	}
+}
+
+// Allows removing the elements matching any of the selectors from the page
+// before it is sent to the provider, eg ".reviews" or "//div[@role='dialog']"
+func WithExcludeSelectors(selectors ...string) Option {
+	return func(r *ScrapeAiRequest) {
+		r.ExcludeSelectors = append(r.ExcludeSelectors, selectors...)
+	}
+}
+
+// Allows specifying which elements and attributes are kept when the page is
+// stripped. The default is scraping.DefaultStripPolicy. Relative URLs are
+// resolved against the page URL unless the policy sets a BaseURL. Invalid
+// selectors in the policy fail NewScrapeAiRequest
+func WithStripPolicy(p scraping.StripPolicy) Option {
+	return func(r *ScrapeAiRequest) {
+		r.StripPolicy = &p
+	}
+}
+
+// Allows sending only the main content of the page to the provider, dropping
+// navigation, headers, footers, sidebars and banners. See
+// scraping.ExtractMainContent. The default is to send the whole page
+func WithMainContent(enabled bool) Option {
+	return func(r *ScrapeAiRequest) {
+		r.MainContent = enabled
+	}
+}
+
+// Allows specifying the maximum number of tokens sent to the provider in a
+// single request. The default is derived from the context window of the
+// provider's model, or 100,000 if the model is not in the llm registry
+func WithMaxChunkTokens(n int) Option {
+	return func(r *ScrapeAiRequest) {
+		r.MaxChunkTokens = n
+	}
+}
+
+// Allows specifying what happens when a page is too large for a single
+// request. The default is OverflowChunk
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(r *ScrapeAiRequest) {
+		r.OverflowPolicy = p
+	}
+}
+
+// Allows specifying the per model prices used to estimate the cost of a
+// scrape. The default is llm.DefaultPrices
+func WithPrices(prices llm.PriceTable) Option {
+	return func(r *ScrapeAiRequest) {
+		r.Prices = prices
+	}
+}
+
+// Allows limiting the tokens and cost spent on the request. The same Budget
+// can be shared between many requests to enforce a cumulative limit
+func WithBudget(b *Budget) Option {
+	return func(r *ScrapeAiRequest) {
+		r.Budget = b
+	}
+}
+
+// Allows specifying how many times a response which is invalid JSON or does
+// not match the schema is sent back to the provider to be corrected before
+// Scrape fails. The default is 1, 0 disables repairs
+func WithRepairAttempts(n int) Option {
+	return func(r *ScrapeAiRequest) {
+		r.RepairAttempts = n
+	}
+}
+
+// Allows specifying how many times a response which was cut off by the output
+// token limit is retried with double the limit, up to the model's maximum.
+// If the response is still truncated the chunk is split into smaller pieces
+// which are extracted separately. The default is 2
+func WithTruncationRetries(n int) Option {
+	return func(r *ScrapeAiRequest) {
+		r.TruncationRetries = n
+	}
+}
+
 // ScrapeAiRequest represents the input for a scraping operation.
 type ScrapeAiRequest struct {
 	Url       string
 	Prompt    string
-	FetchFunc FetchFunc // Optional custom fetch function
-	Schema    string    // Optional custom schema for the response
+	FetchFunc FetchFunc    // Optional custom fetch function
+	Fetcher   Fetcher      // Optional custom fetcher, used in place of FetchFunc
+	Schema    string       // Optional custom schema for the response
+	Provider  llm.Provider // Optional custom LLM backend
+	// Optional form the page is sent in, HTML by default
+	ContentFormat ContentFormat
+	// Optional removal of page chrome, keeping only the main content
+	MainContent bool
+	// Optional CSS or XPath selectors the page is narrowed to
+	Selectors []string
+	// Optional CSS or XPath selectors removed from the page
+	ExcludeSelectors []string
+	// Optional elements and attributes kept when stripping the page
+	StripPolicy *scraping.StripPolicy
+	// Optional maximum number of tokens per request before chunking
+	MaxChunkTokens int
+	// Optional handling of pages which exceed MaxChunkTokens
+	OverflowPolicy OverflowPolicy
+	// Optional prices used to estimate cost
+	Prices llm.PriceTable
+	// Optional limits on tokens and cost
+	Budget *Budget
+	// Optional number of attempts to correct invalid responses
+	RepairAttempts int
+	// Optional number of retries with a larger output limit after truncation
+	TruncationRetries int
 }
 
 // Initialise a new ScrapeAiRequest object with options and sensible
 // defaults
 func NewScrapeAiRequest(url string, prompt string, options ...Option) (*ScrapeAiRequest, error) {
-	req := &ScrapeAiRequest{Url: url, Prompt: prompt}
+	req := &ScrapeAiRequest{
+		Url:               url,
+		Prompt:            prompt,
+		RepairAttempts:    defaultRepairAttempts,
+		TruncationRetries: defaultTruncationRetries,
+	}
 	for _, o := range options {
 		o(req)
 	}
-	if req.FetchFunc == nil {
-		req.FetchFunc = defaultFetchFunc
+	if req.Fetcher == nil && req.FetchFunc != nil {
+		req.Fetcher = scraping.FromFetchFunc(req.FetchFunc)
+	}
+	if req.Fetcher == nil {
+		req.Fetcher = defaultFetcher
+	}
+	if req.Prices == nil {
+		req.Prices = llm.DefaultPrices
+	}
+	if req.Provider == nil {
+		req.Provider = gpt.NewProvider()
+	}
+	for _, selector := range slices.Concat(req.Selectors, req.ExcludeSelectors) {
+		if err := scraping.ValidateSelector(selector); err != nil {
+			return nil, err
+		}
+	}
+	if req.StripPolicy != nil {
+		if err := req.StripPolicy.Validate(); err != nil {
+			return nil, err
+		}
 	}
 	if req.Schema != "" {
 		err := gpt.ValidateSchema(req.Schema)
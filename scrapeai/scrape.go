@@ -3,11 +3,15 @@
 package scrapeai
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/llm"
 	"github.com/samredway/scrapeai/scraping"
 )
 
@@ -15,60 +19,242 @@ const Version = "v0.4.2"
 
 // ScrapeAiResult contains the results of a scraping operation.
 type ScrapeAiResult struct {
-	Url     string
-	Results string
+	Url      string
+	Results  string
+	Usage    Usage    // Tokens used and estimated cost across all provider calls
+	Refusals []string // Explanations for parts of the page the model declined to extract
+	// The fetched page with its status, headers, final URL and timing, as far
+	// as the fetcher is able to report them
+	Page *FetchResult
 }
 
 // Scrape performs a web scraping operation with AI assistance.
 func Scrape(ctx context.Context, req *ScrapeAiRequest) (*ScrapeAiResult, error) {
-	page, err := req.FetchFunc(ctx, req.Url)
+	fetched, err := req.Fetcher.Fetch(ctx, req.Url)
 	if err != nil {
+		// errors from custom fetch functions are wrapped so that every fetch
+		// failure can be detected with errors.As
+		var fetchErr *FetchError
+		if !errors.As(err, &fetchErr) {
+			err = &FetchError{URL: req.Url, Err: err}
+		}
 		return nil, fmt.Errorf("fetching page: %w", err)
 	}
 
 	// scrape the page
-	goqueryDoc, err := scraping.GoQueryDocFromBody(page)
+	goqueryDoc, err := scraping.GoQueryDocFromBody(fetched.Text())
 	if err != nil {
 		return nil, fmt.Errorf("creating goquery doc: %w", err)
 	}
-	strippedPage := scraping.StripNonTextTags(goqueryDoc)
+	if len(req.Selectors) > 0 {
+		goqueryDoc, err = scraping.SelectContent(goqueryDoc, req.Selectors...)
+		if err != nil {
+			return nil, fmt.Errorf("selecting content: %w", err)
+		}
+	}
+	if len(req.ExcludeSelectors) > 0 {
+		goqueryDoc, err = scraping.RemoveContent(goqueryDoc, req.ExcludeSelectors...)
+		if err != nil {
+			return nil, fmt.Errorf("removing excluded content: %w", err)
+		}
+	}
+	if req.MainContent {
+		goqueryDoc = scraping.ExtractMainContent(goqueryDoc)
+	}
+	policy := scraping.DefaultStripPolicy
+	if req.StripPolicy != nil {
+		policy = *req.StripPolicy
+	}
+	if policy.BaseURL == "" {
+		policy.BaseURL = cmp.Or(fetched.URL, req.Url)
+	}
+	strippedPage := scraping.StripWithPolicy(goqueryDoc, policy)
 	pageText, err := scraping.GetDocumentHTML(strippedPage)
 	if err != nil {
 		return nil, fmt.Errorf("getting document HTML: %w", err)
 	}
 
-	// get the results of search from GPT
-	results, err := processWithGPT(req, pageText)
+	// split or truncate pages which are too large for a single request
+	model, count, limit := requestLimits(req)
+	chunks := []string{pageText}
+	if tokens := count(req.Prompt + "\n\n" + req.ContentFormat.render(pageText)); tokens > limit {
+		if req.OverflowPolicy == OverflowFail {
+			return nil, &TokenLimitError{Model: model, Tokens: tokens, Limit: limit}
+		}
+		budget := max(limit-count(req.Prompt), 1)
+		if split := scraping.ChunkDocument(strippedPage, budget, req.ContentFormat.counter(count)); len(split) > 0 {
+			chunks = split
+		}
+		if req.OverflowPolicy == OverflowTruncate {
+			chunks = chunks[:1]
+		}
+	}
+
+	// get the results of search from the LLM provider for each chunk
+	var usage Usage
+	var refusals []string
+	chunkResults := make([]string, 0, len(chunks))
+	for i := 0; i < len(chunks); i++ {
+		chunk := chunks[i]
+		page, hold, err := reserveBudget(req, chunk, model, count, &usage)
+		if err != nil {
+			// under the truncate policy keep what has been extracted so far
+			if errors.Is(err, ErrBudgetExceeded) && req.Budget.OnExceeded == BudgetTruncate && len(chunkResults) > 0 {
+				break
+			}
+			return nil, err
+		}
+
+		result, err := processWithProvider(ctx, req, req.ContentFormat.render(page), model, count, hold, &usage)
+		exhausted := false
+
+		var refusal *RefusalError
+		switch {
+		case errors.Is(err, ErrBudgetExceeded) && req.Budget.OnExceeded == BudgetTruncate && len(chunkResults) > 0:
+			// keep what has been extracted so far
+			exhausted = true
+		case errors.As(err, &refusal):
+			refusals = append(refusals, refusal.Message)
+		case errors.Is(err, ErrTruncated) && page == chunk:
+			// the response does not fit in the output limit so extract the
+			// chunk in smaller pieces instead
+			parts := splitChunk(chunk, req.ContentFormat.counter(count))
+			if parts == nil {
+				return nil, fmt.Errorf("processing chunk %d of %d with provider: %w", i+1, len(chunks), err)
+			}
+			chunks = slices.Insert(chunks, i+1, parts...)
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("processing chunk %d of %d with provider: %w", i+1, len(chunks), err)
+		default:
+			chunkResults = append(chunkResults, result)
+		}
+
+		// the budget has run out so the remaining chunks are skipped
+		if page != chunk || exhausted {
+			break
+		}
+	}
+
+	// a refusal is only an error when nothing could be extracted at all
+	if len(chunkResults) == 0 && len(refusals) > 0 {
+		return nil, &RefusalError{Message: refusals[0]}
+	}
+
+	results, err := mergeResults(req.Schema, chunkResults)
 	if err != nil {
-		return nil, fmt.Errorf("processing with GPT: %w", err)
+		return nil, fmt.Errorf("merging chunk results: %w", err)
 	}
 
 	return &ScrapeAiResult{
-		Url:     req.Url,
-		Results: results,
+		Url:      req.Url,
+		Results:  results,
+		Usage:    usage,
+		Refusals: refusals,
+		Page:     fetched,
 	}, nil
 }
 
-func processWithGPT(req *ScrapeAiRequest, pageText string) (string, error) {
-	gptRequest := gpt.NewGptRequest(req.Prompt, pageText)
-	if req.Schema != "" {
-		gptRequest.SetSchema(req.Schema)
+// processWithProvider extracts results from a single chunk of the page,
+// recording the usage of each call. Responses which are not valid JSON or do
+// not match the schema are sent back to the provider to be corrected, up to
+// the request's RepairAttempts, and truncated responses are retried with a
+// larger output limit up to the request's TruncationRetries. The first call
+// is made with the budget already held for it, and room is reserved for each
+// repair and retry before it is made
+func processWithProvider(
+	ctx context.Context,
+	req *ScrapeAiRequest,
+	pageText string,
+	model string,
+	count scraping.TokenCounter,
+	hold *reservation,
+	usage *Usage,
+) (string, error) {
+	llmRequest := &llm.Request{
+		Prompt: req.Prompt,
+		Page:   pageText,
+		Schema: req.Schema,
 	}
 
-	response, err := gpt.SendGptRequest(gptRequest)
-	if err != nil {
-		return "", err
+	repairs, truncations := 0, 0
+	outputLimit := 0 // zero for the provider's default
+	for call := 0; ; call++ {
+		if call > 0 {
+			var err error
+			if hold, err = reserveCall(req, count(callInput(llmRequest)), model, usage); err != nil {
+				return "", err
+			}
+		}
+		ceiling := 0
+		if info, ok := llm.LookupModel(cmp.Or(model, usage.Model())); ok {
+			ceiling = info.MaxOutputTokens
+		}
+		maxTokens, capped, err := hold.limitOutput(outputLimit, ceiling, usage)
+		if err != nil {
+			hold.settle(nil)
+			return "", err
+		}
+		llmRequest.MaxTokens = maxTokens
+
+		response, err := req.Provider.Extract(ctx, llmRequest)
+		if err != nil {
+			hold.settle(nil)
+			return "", err
+		}
+		usage.add(response, model, req.Prices)
+		hold.settle(usage.Calls[len(usage.Calls)-1:])
+
+		if response.Refusal != "" {
+			return "", &RefusalError{Message: response.Refusal}
+		}
+		if response.Truncated() {
+			if capped {
+				return "", fmt.Errorf("%w: the response did not fit in the remaining budget", ErrBudgetExceeded)
+			}
+			next, ok := nextMaxTokens(model, outputLimit, response.Usage.CompletionTokens)
+			if !ok || truncations >= req.TruncationRetries {
+				return "", fmt.Errorf("%w: the model reached its output token limit", ErrTruncated)
+			}
+			truncations++
+			outputLimit = next
+			continue
+		}
+
+		// Get the raw response content
+		content := response.Content
+
+		problem := validateContent(req.Schema, content)
+		if problem == nil {
+			return content, nil
+		}
+		if repairs >= req.RepairAttempts {
+			return "", problem
+		}
+		repairs++
+		llmRequest.Repair = &llm.Repair{Response: content, Problem: problem.Error()}
 	}
+}
 
-	// Get the raw response content
-	content := response.Choices[0].Message.Content
+// callInput is the text sent to the provider for a call, for counting its
+// tokens
+func callInput(req *llm.Request) string {
+	input := req.Prompt + "\n\n" + req.Page
+	if req.Repair != nil {
+		input += "\n\n" + req.Repair.Response + "\n\n" + llm.RepairInstructions(req.Repair.Problem)
+	}
+	return input
+}
 
+// validateContent checks the content is valid JSON which matches the schema
+func validateContent(schema string, content string) error {
 	// Unmarshal into a generic structure to validate JSON
 	var rawJSON any
 	if err := json.Unmarshal([]byte(content), &rawJSON); err != nil {
-		return "", fmt.Errorf("invalid JSON response: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidJSON, err)
 	}
-
-	// If we get here, the JSON is valid
-	return content, nil
+	if err := gpt.ValidateResponse(schema, content); err != nil {
+		return fmt.Errorf("%w: %w", ErrSchemaMismatch, err)
+	}
+	return nil
 }
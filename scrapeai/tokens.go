@@ -0,0 +1,102 @@
+package scrapeai
+
+import (
+	"fmt"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scraping"
+	"github.com/samredway/scrapeai/tokenizer"
+)
+
+// default maximum number of tokens sent to a provider whose model is unknown
+const defaultMaxChunkTokens = 100_000
+
+// tokens reserved for the chat message framing around the prompt and schema
+const messageOverheadTokens = 100
+
+// OverflowPolicy controls what happens when a page is too large to be sent to
+// the provider in a single request
+type OverflowPolicy int
+
+const (
+	// Split the page into chunks, extract each and merge the results
+	OverflowChunk OverflowPolicy = iota
+	// Send only as much of the start of the page as fits in one request
+	OverflowTruncate
+	// Return a TokenLimitError without calling the provider
+	OverflowFail
+)
+
+// TokenLimitError is returned when a request exceeds the token limit of the
+// model and the OverflowFail policy is in use
+type TokenLimitError struct {
+	Model  string
+	Tokens int
+	Limit  int
+}
+
+func (e *TokenLimitError) Error() string {
+	return fmt.Sprintf("request of %d tokens exceeds the limit of %d tokens for model %q", e.Tokens, e.Limit, e.Model)
+}
+
+// requestLimits returns the model used by the request's provider, a token
+// counter for that model and the maximum number of input tokens per request
+func requestLimits(req *ScrapeAiRequest) (string, scraping.TokenCounter, int) {
+	model := ""
+	if namer, ok := req.Provider.(llm.ModelNamer); ok {
+		model = namer.Model()
+	}
+
+	count := scraping.TokenCounter(scraping.EstimateTokens)
+	info, known := llm.LookupModel(model)
+	if known {
+		if enc, err := tokenizer.GetEncoding(info.Encoding); err == nil {
+			count = enc.Count
+		}
+	}
+
+	limit := req.MaxChunkTokens
+	if limit <= 0 {
+		limit = defaultMaxChunkTokens
+		if known {
+			// leave room for the response, but never more than a quarter of the
+			// window for models whose output limit is the whole window
+			reserved := min(info.MaxOutputTokens, info.ContextWindow/4)
+			limit = info.ContextWindow - reserved - count(req.Schema) - messageOverheadTokens
+		}
+	}
+	return model, count, limit
+}
+
+// nextMaxTokens returns a larger output token limit to retry a truncated
+// response with, capped at the model's maximum output if it is known. It
+// reports false if the limit cannot grow any further
+func nextMaxTokens(model string, current int, used int) (int, bool) {
+	previous := max(current, used)
+	next := previous * 2
+	if info, ok := llm.LookupModel(model); ok && info.MaxOutputTokens > 0 {
+		next = min(next, info.MaxOutputTokens)
+	}
+	return next, next > previous
+}
+
+// chunks smaller than this are not split after a truncated response, as the
+// size of the page is unlikely to be the cause
+const minSplitTokens = 256
+
+// splitChunk splits a chunk of HTML which produced a truncated response into
+// pieces of roughly half its size, or returns nil if it cannot be split
+func splitChunk(chunk string, count scraping.TokenCounter) []string {
+	if count(chunk) < minSplitTokens {
+		return nil
+	}
+	doc, err := scraping.GoQueryDocFromBody(chunk)
+	if err != nil {
+		return nil
+	}
+	parts := scraping.ChunkDocument(doc, max((count(chunk)+1)/2, 1), count)
+	if len(parts) < 2 {
+		return nil
+	}
+	return parts
+}
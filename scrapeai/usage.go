@@ -0,0 +1,63 @@
+package scrapeai
+
+import (
+	"github.com/samredway/scrapeai/llm"
+)
+
+// Usage reports the tokens used and the estimated cost of a scrape summed
+// across every call made to the provider, eg one call per chunk of a large
+// page. Failed attempts which the provider retried are not billed and so are
+// not included
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// Estimated cost in US dollars. Calls to models missing from the price
+	// table contribute nothing, see Priced
+	EstimatedCost float64
+	// Whether every call was made to a model with a known price
+	Priced bool
+	Calls  []CallUsage
+}
+
+// CallUsage reports the usage of a single call to the provider
+type CallUsage struct {
+	Model         string // Model which served the call
+	ResponseID    string
+	FinishReason  string
+	Usage         llm.Usage
+	EstimatedCost float64
+	Priced        bool
+}
+
+// Model returns the model used for the scrape, which is the model reported by
+// the most recent call
+func (u *Usage) Model() string {
+	if len(u.Calls) == 0 {
+		return ""
+	}
+	return u.Calls[len(u.Calls)-1].Model
+}
+
+// add records a provider response, falling back to the requested model for
+// servers which do not report the model they used
+func (u *Usage) add(response *llm.Response, model string, prices llm.PriceTable) {
+	if response.Model != "" {
+		model = response.Model
+	}
+	cost, priced := prices.Cost(model, response.Usage)
+
+	u.PromptTokens += response.Usage.PromptTokens
+	u.CompletionTokens += response.Usage.CompletionTokens
+	u.TotalTokens += response.Usage.TotalTokens
+	u.EstimatedCost += cost
+	u.Priced = priced && (u.Priced || len(u.Calls) == 0)
+	u.Calls = append(u.Calls, CallUsage{
+		Model:         model,
+		ResponseID:    response.ID,
+		FinishReason:  response.FinishReason,
+		Usage:         response.Usage,
+		EstimatedCost: cost,
+		Priced:        priced,
+	})
+}
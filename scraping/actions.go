@@ -0,0 +1,153 @@
+package scraping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Action interacts with a page rendered in a browser before its HTML is
+// captured, eg to accept a cookie banner or load more results. Actions which
+// wait for an element, such as Click, wait until the element exists or the
+// context is done, see IfPresent for elements which may not exist
+type Action struct {
+	name string
+	run  func(ctx context.Context, activity *networkActivity) error
+}
+
+// Click clicks the element matching a CSS selector or XPath expression
+func Click(selector string) Action {
+	return chromedpAction(fmt.Sprintf("click %q", selector), chromedp.Click(selector, queryBy(selector)))
+}
+
+// Type focuses the element matching a selector and types text into it. Keys
+// such as enter can be sent with the constants of chromedp's kb package, eg
+// "shoes" + kb.Enter
+func Type(selector string, text string) Action {
+	return chromedpAction(fmt.Sprintf("type into %q", selector), chromedp.SendKeys(selector, text, queryBy(selector)))
+}
+
+// ScrollTo scrolls the element matching a selector into view
+func ScrollTo(selector string) Action {
+	return chromedpAction(fmt.Sprintf("scroll to %q", selector), chromedp.ScrollIntoView(selector, queryBy(selector)))
+}
+
+// ScrollToBottom scrolls to the bottom of the page, which triggers lazy
+// loading on many sites
+func ScrollToBottom() Action {
+	return chromedpAction("scroll to bottom",
+		chromedp.Evaluate(`window.scrollTo(0, document.documentElement.scrollHeight)`, nil))
+}
+
+// Select chooses the option with the given value of the select element
+// matching a selector, firing the input and change events as a user would
+func Select(selector string, value string) Action {
+	name := fmt.Sprintf("select %q in %q", value, selector)
+	return Action{name, func(ctx context.Context, activity *networkActivity) error {
+		if err := chromedp.Run(ctx, chromedp.WaitVisible(selector, queryBy(selector))); err != nil {
+			return err
+		}
+		script := fmt.Sprintf(`((el, value) => {
+			el.value = value;
+			el.dispatchEvent(new Event("input", {bubbles: true}));
+			el.dispatchEvent(new Event("change", {bubbles: true}));
+			return el.value === value;
+		})(%s, %s)`, findElementJS(selector), jsString(value))
+		var ok bool
+		if err := chromedp.Run(ctx, chromedp.Evaluate(script, &ok)); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no option with value %q", value)
+		}
+		return nil
+	}}
+}
+
+// Evaluate runs JavaScript in the page
+func Evaluate(script string) Action {
+	return chromedpAction("evaluate script", chromedp.Evaluate(script, nil))
+}
+
+// Wait waits for all of the conditions, eg for new results to load after
+// clicking a button
+func Wait(conditions ...WaitCondition) Action {
+	condition := WaitAll(conditions...)
+	return Action{"wait", func(ctx context.Context, activity *networkActivity) error {
+		return condition.run(ctx, activity)
+	}}
+}
+
+// IfPresent runs the actions only if an element matching the selector exists
+// at that point, eg to dismiss a cookie banner which is not always shown
+func IfPresent(selector string, actions ...Action) Action {
+	return Action{fmt.Sprintf("if %q is present", selector), func(ctx context.Context, activity *networkActivity) error {
+		var present bool
+		if err := chromedp.Run(ctx, chromedp.Evaluate(findElementJS(selector)+" !== null", &present)); err != nil {
+			return err
+		}
+		if !present {
+			return nil
+		}
+		return runActions(ctx, activity, actions)
+	}}
+}
+
+// Repeat runs the actions a number of times, eg to click "load more" several
+// times with IfPresent stopping once the button is gone
+func Repeat(times int, actions ...Action) Action {
+	return Action{fmt.Sprintf("repeat %d times", times), func(ctx context.Context, activity *networkActivity) error {
+		for range times {
+			if err := runActions(ctx, activity, actions); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// runActions runs each of the actions in turn
+func runActions(ctx context.Context, activity *networkActivity, actions []Action) error {
+	for _, action := range actions {
+		if action.run == nil {
+			continue
+		}
+		if err := action.run(ctx, activity); err != nil {
+			return fmt.Errorf("%s: %w", action.name, err)
+		}
+	}
+	return nil
+}
+
+func chromedpAction(name string, action chromedp.Action) Action {
+	return Action{name, func(ctx context.Context, activity *networkActivity) error {
+		return chromedp.Run(ctx, action)
+	}}
+}
+
+// queryBy returns the chromedp query option for a CSS selector or XPath
+// expression
+func queryBy(selector string) chromedp.QueryOption {
+	if isXPath(selector) {
+		return chromedp.BySearch
+	}
+	return chromedp.ByQuery
+}
+
+// findElementJS returns a JavaScript expression evaluating to the first
+// element matching a selector, or null
+func findElementJS(selector string) string {
+	if isXPath(selector) {
+		return fmt.Sprintf("document.evaluate(%s, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue",
+			jsString(selector))
+	}
+	return fmt.Sprintf("document.querySelector(%s)", jsString(selector))
+}
+
+// jsString quotes a string as a JavaScript string literal
+func jsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,307 @@
+package scraping
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	defaultPoolBrowsers = 2
+	defaultPoolTabs     = 4
+	defaultPoolMaxPages = 100
+
+	// time allowed for a tab to be cleared before it is reused
+	tabResetTimeout = 5 * time.Second
+)
+
+// BrowserPool renders pages in a set of headless browsers which are kept
+// running between fetches, avoiding the cost of launching a browser for every
+// page as FetchChromedp does. Each browser renders several pages at once in
+// separate tabs, which are kept open and reused for later pages. Tabs are
+// closed rather than reused when a page fails, since their state is unknown.
+// Browsers are replaced after a number of pages, to bound the memory they use,
+// or when they crash.
+//
+// A BrowserPool is safe for concurrent use. Browsers are launched when they
+// are first needed and Close must be called to shut them down
+type BrowserPool struct {
+	browsers     int
+	tabs         int
+	maxPages     int
+	allocOptions []chromedp.ExecAllocatorOption
+	newBrowser   func() Browser
+
+	slots    chan struct{} // one for each tab in use
+	mu       sync.Mutex
+	live     []*pooledBrowser // browsers which are given new pages
+	retiring []*pooledBrowser // browsers finishing their pages before shutting down
+	closed   bool
+}
+
+type pooledBrowser struct {
+	browser  Browser
+	start    sync.Once
+	startErr error
+	pages    int          // pages given to the browser
+	active   int          // pages being rendered
+	idle     []BrowserTab // open tabs waiting for a page
+}
+
+// Browser is a browser which a BrowserPool renders pages in. The default is a
+// headless Chrome driven by chromedp, see WithBrowserFactory
+type Browser interface {
+	// Start launches the browser. It is called once, before any tab is opened
+	Start() error
+	// OpenTab opens a new tab in the browser
+	OpenTab() (BrowserTab, error)
+	// Alive reports whether the browser is still running
+	Alive() bool
+	// Close shuts the browser down along with its tabs
+	Close()
+}
+
+// BrowserTab is a tab of a Browser, which renders one page at a time
+type BrowserTab interface {
+	// Render renders a url configured by the options, also reporting whether
+	// the browser crashed
+	Render(ctx context.Context, url string, options []PageOption) (*FetchResult, bool, error)
+	// Reset clears the page rendered in the tab, reporting whether the tab can
+	// be reused
+	Reset() bool
+	// Close closes the tab
+	Close()
+}
+
+// BrowserOption configures a BrowserPool
+type BrowserOption func(*BrowserPool)
+
+// WithBrowsers sets the number of browsers kept running. Defaults to 2
+func WithBrowsers(n int) BrowserOption {
+	return func(p *BrowserPool) {
+		p.browsers = n
+	}
+}
+
+// WithTabsPerBrowser sets the number of pages each browser renders at once.
+// Defaults to 4
+func WithTabsPerBrowser(n int) BrowserOption {
+	return func(p *BrowserPool) {
+		p.tabs = n
+	}
+}
+
+// WithMaxPagesPerBrowser replaces a browser once it has rendered n pages.
+// Defaults to 100, zero means browsers are only replaced when they crash
+func WithMaxPagesPerBrowser(n int) BrowserOption {
+	return func(p *BrowserPool) {
+		p.maxPages = n
+	}
+}
+
+// WithAllocatorOptions sets the options browsers are launched with, such as
+// chromedp.ExecPath or chromedp.ProxyServer. Defaults to
+// chromedp.DefaultExecAllocatorOptions
+func WithAllocatorOptions(options ...chromedp.ExecAllocatorOption) BrowserOption {
+	return func(p *BrowserPool) {
+		p.allocOptions = options
+	}
+}
+
+// WithBrowserFactory sets the function creating the pool's browsers, eg to
+// render pages with a remote browser service. The allocator options are
+// ignored. Defaults to launching headless Chrome with chromedp
+func WithBrowserFactory(newBrowser func() Browser) BrowserOption {
+	return func(p *BrowserPool) {
+		p.newBrowser = newBrowser
+	}
+}
+
+// NewBrowserPool returns a BrowserPool configured by the options
+func NewBrowserPool(options ...BrowserOption) *BrowserPool {
+	p := &BrowserPool{
+		browsers:     defaultPoolBrowsers,
+		tabs:         defaultPoolTabs,
+		maxPages:     defaultPoolMaxPages,
+		allocOptions: chromedp.DefaultExecAllocatorOptions[:],
+	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.newBrowser == nil {
+		p.newBrowser = func() Browser {
+			return newChromeBrowser(p.allocOptions)
+		}
+	}
+	p.browsers = max(p.browsers, 1)
+	p.tabs = max(p.tabs, 1)
+	p.slots = make(chan struct{}, p.browsers*p.tabs)
+	return p
+}
+
+// Fetch renders a url in one of the pool's browsers, returning the rendered
+// HTML along with the metadata of the document response. It waits for a tab
+// to be free if all of them are in use. Pages which load with an error status
+// return a *FetchError
+func (p *BrowserPool) Fetch(ctx context.Context, url string) (*FetchResult, error) {
+	return p.fetch(ctx, url, nil)
+}
+
+// Fetcher returns a Fetcher which renders pages in the pool's browsers
+// configured by the options, eg to wait for a particular element on some
+// sites while sharing browsers with others
+func (p *BrowserPool) Fetcher(options ...PageOption) Fetcher {
+	return FetcherFunc(func(ctx context.Context, url string) (*FetchResult, error) {
+		return p.fetch(ctx, url, options)
+	})
+}
+
+func (p *BrowserPool) fetch(ctx context.Context, url string, options []PageOption) (*FetchResult, error) {
+	b, err := p.acquire(ctx)
+	if err != nil {
+		return nil, &FetchError{URL: url, Err: err}
+	}
+
+	tab, err := p.openTab(b)
+	if err != nil {
+		p.release(b, nil, !b.browser.Alive())
+		return nil, &FetchError{URL: url, Err: fmt.Errorf("opening tab: %w", err)}
+	}
+
+	result, crashed, err := tab.Render(ctx, url, options)
+	if err != nil || crashed || ctx.Err() != nil || !tab.Reset() {
+		tab.Close()
+		tab = nil
+	}
+	p.release(b, tab, crashed || !b.browser.Alive())
+	return result, err
+}
+
+// openTab returns an idle tab of the browser, or opens a new one
+func (p *BrowserPool) openTab(b *pooledBrowser) (BrowserTab, error) {
+	p.mu.Lock()
+	if n := len(b.idle); n > 0 {
+		tab := b.idle[n-1]
+		b.idle = b.idle[:n-1]
+		p.mu.Unlock()
+		return tab, nil
+	}
+	p.mu.Unlock()
+	return b.browser.OpenTab()
+}
+
+// FetchFunc returns a function rendering pages in the pool, for use where a
+// function returning only the body is expected such as scrapeai.WithFetchFunc
+func (p *BrowserPool) FetchFunc() func(ctx context.Context, url string) (string, error) {
+	return func(ctx context.Context, url string) (string, error) {
+		result, err := p.Fetch(ctx, url)
+		if err != nil {
+			return "", err
+		}
+		return result.Text(), nil
+	}
+}
+
+// Close shuts down the pool's browsers, cancelling any pages being rendered
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, b := range p.live {
+		b.browser.Close()
+	}
+	for _, b := range p.retiring {
+		b.browser.Close()
+	}
+	p.live, p.retiring = nil, nil
+}
+
+// acquire waits for a free tab and returns the browser to open it in,
+// launching a browser if needed
+func (p *BrowserPool) acquire(ctx context.Context) (*pooledBrowser, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, ErrPoolClosed
+	}
+	b := p.choose()
+	b.active++
+	b.pages++
+	if p.maxPages > 0 && b.pages >= p.maxPages {
+		p.retire(b)
+	}
+	p.mu.Unlock()
+
+	b.start.Do(func() {
+		b.startErr = b.browser.Start()
+	})
+	if b.startErr != nil {
+		p.release(b, nil, true)
+		return nil, fmt.Errorf("launching browser: %w", b.startErr)
+	}
+	return b, nil
+}
+
+// choose returns the live browser with the fewest pages being rendered,
+// launching a new one while there are fewer than the pool's size
+func (p *BrowserPool) choose() *pooledBrowser {
+	var chosen *pooledBrowser
+	for _, b := range p.live {
+		if chosen == nil || b.active < chosen.active {
+			chosen = b
+		}
+	}
+	if chosen != nil && (chosen.active < p.tabs || len(p.live) >= p.browsers) {
+		return chosen
+	}
+
+	b := &pooledBrowser{browser: p.newBrowser()}
+	p.live = append(p.live, b)
+	return b
+}
+
+// release frees a tab of the browser, replacing the browser if it is broken.
+// The tab, if any, is kept for the next page while the browser is live
+func (p *BrowserPool) release(b *pooledBrowser, tab BrowserTab, broken bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer func() { <-p.slots }()
+	b.active--
+	if broken {
+		p.retire(b)
+	}
+	if tab != nil {
+		if slices.Contains(p.live, b) && len(b.idle) < p.tabs {
+			b.idle = append(b.idle, tab)
+		} else {
+			// closing waits for the browser, so is done without the lock
+			go tab.Close()
+		}
+	}
+	if b.active == 0 {
+		if i := slices.Index(p.retiring, b); i >= 0 {
+			p.retiring = slices.Delete(p.retiring, i, i+1)
+			b.browser.Close()
+		}
+	}
+}
+
+// retire stops a browser being given new pages. It is shut down once its
+// pages have been rendered
+func (p *BrowserPool) retire(b *pooledBrowser) {
+	if i := slices.Index(p.live, b); i >= 0 {
+		p.live = slices.Delete(p.live, i, i+1)
+		p.retiring = append(p.retiring, b)
+	}
+}
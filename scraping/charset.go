@@ -0,0 +1,59 @@
+package scraping
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// a <meta charset> or http-equiv declaration anywhere in the head, for pages
+// which declare their charset beyond the 1024 bytes browsers prescan
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([\w.:-]+)`)
+
+// how much of a page is searched for a <meta charset> declaration
+const maxMetaSearch = 64 << 10
+
+// DecodeHTML converts an HTML page to UTF-8, returning the body along with the
+// name of the encoding it was decoded from. As in a browser the encoding is
+// taken from a byte order mark, then the charset of the Content-Type header
+// and then a <meta charset> element. Pages which declare no charset are left
+// as they are when they are valid UTF-8 and otherwise read as windows-1252
+func DecodeHTML(body []byte, contentType string) ([]byte, string, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain {
+		switch {
+		case utf8.Valid(body):
+			return body, "utf-8", nil
+		case len(body) > 1024:
+			if match := metaCharset.FindSubmatch(body[:min(len(body), maxMetaSearch)]); match != nil {
+				if e, n := charset.Lookup(string(match[1])); e != nil {
+					enc, name = e, n
+				}
+			}
+		}
+	}
+	if name == "utf-8" {
+		return bytes.TrimPrefix(body, utf8BOM), name, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding page from %s: %w", name, err)
+	}
+	return decoded, name, nil
+}
+
+// isText reports whether a media type is text which should be decoded, as
+// opposed to binary content such as an image. An unknown type is assumed to
+// be HTML
+func isText(mediaType string) bool {
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/json" || mediaType == "application/javascript"
+}
@@ -0,0 +1,201 @@
+package scraping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/inspector"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// pageOptions controls how a page is rendered in a browser
+type pageOptions struct {
+	wait    WaitCondition
+	actions []Action
+}
+
+// PageOption configures how pages are rendered by NewChromedpFetcher and
+// BrowserPool.Fetcher
+type PageOption func(*pageOptions)
+
+// WithWait sets the conditions waited for before the HTML of a page is
+// captured, in place of DefaultWait. With several conditions all of them are
+// waited for, see WaitAny to wait for the first
+func WithWait(conditions ...WaitCondition) PageOption {
+	return func(o *pageOptions) {
+		o.wait = WaitAll(conditions...)
+	}
+}
+
+// WithActions sets actions which are run in turn once the page is ready,
+// before its HTML is captured. The page is captured straight after the last
+// action, so end with Wait if the actions load more content
+func WithActions(actions ...Action) PageOption {
+	return func(o *pageOptions) {
+		o.actions = actions
+	}
+}
+
+func newPageOptions(options []PageOption) pageOptions {
+	o := pageOptions{wait: DefaultWait}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+// NewChromedpFetcher returns a Fetcher which renders pages as FetchChromedp
+// does, configured by the options, eg
+//
+//	scraping.NewChromedpFetcher(scraping.WithWait(scraping.WaitVisible("#results")))
+func NewChromedpFetcher(options ...PageOption) Fetcher {
+	o := newPageOptions(options)
+	return FetcherFunc(func(ctx context.Context, url string) (*FetchResult, error) {
+		chromedpCtx, cancel := chromedp.NewContext(
+			ctx,
+			chromedp.WithLogf(func(string, ...any) {}),
+		)
+		defer cancel()
+
+		result, _, err := fetchInTab(chromedpCtx, url, o)
+		return result, err
+	})
+}
+
+// chromeBrowser is a headless Chrome driven by chromedp, the default Browser
+// of a BrowserPool
+type chromeBrowser struct {
+	ctx    context.Context    // tabs are opened from the browser's context
+	cancel context.CancelFunc // shuts the browser down
+}
+
+func newChromeBrowser(allocOptions []chromedp.ExecAllocatorOption) *chromeBrowser {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), allocOptions...)
+	browserCtx, cancelBrowser := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithLogf(func(string, ...any) {}),
+	)
+	return &chromeBrowser{
+		ctx: browserCtx,
+		cancel: func() {
+			cancelBrowser()
+			cancelAlloc()
+		},
+	}
+}
+
+func (b *chromeBrowser) Start() error {
+	// running no actions launches the browser
+	return chromedp.Run(b.ctx)
+}
+
+func (b *chromeBrowser) OpenTab() (BrowserTab, error) {
+	ctx, cancel := chromedp.NewContext(b.ctx)
+	// running no actions opens the tab
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	return &chromeTab{ctx: ctx, cancel: cancel}, nil
+}
+
+func (b *chromeBrowser) Alive() bool {
+	return b.ctx.Err() == nil
+}
+
+func (b *chromeBrowser) Close() {
+	b.cancel()
+}
+
+type chromeTab struct {
+	ctx    context.Context    // pages are rendered in contexts derived from the tab's
+	cancel context.CancelFunc // closes the tab
+}
+
+func (t *chromeTab) Render(ctx context.Context, url string, options []PageOption) (*FetchResult, bool, error) {
+	// listeners added while rendering are removed when pageCtx is cancelled,
+	// leaving the tab ready for the next page
+	pageCtx, cancelPage := context.WithCancel(t.ctx)
+	defer cancelPage()
+	stop := context.AfterFunc(ctx, cancelPage)
+	defer stop()
+	return fetchInTab(pageCtx, url, newPageOptions(options))
+}
+
+// Reset navigates the tab to a blank page, stopping the scripts and requests
+// of the page rendered in it
+func (t *chromeTab) Reset() bool {
+	ctx, cancel := context.WithTimeout(t.ctx, tabResetTimeout)
+	defer cancel()
+	return chromedp.Run(ctx, chromedp.Navigate("about:blank")) == nil
+}
+
+func (t *chromeTab) Close() {
+	t.cancel()
+}
+
+// fetchInTab renders a url in the browser tab of a chromedp context. It also
+// reports whether the tab crashed
+func fetchInTab(ctx context.Context, url string, options pageOptions) (*FetchResult, bool, error) {
+	start := time.Now()
+	// record the response for the page itself, which is the first document
+	// response after any redirects
+	var mu sync.Mutex
+	var document *network.Response
+	crashed := false
+	activity := newNetworkActivity()
+	chromedp.ListenTarget(ctx, func(ev any) {
+		activity.record(ev)
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev := ev.(type) {
+		case *network.EventResponseReceived:
+			if ev.Type == network.ResourceTypeDocument && document == nil {
+				document = ev.Response
+			}
+		case *inspector.EventTargetCrashed:
+			crashed = true
+		}
+	})
+	isCrashed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return crashed
+	}
+
+	if err := chromedp.Run(ctx, network.Enable(), chromedp.Navigate(url)); err != nil {
+		return nil, isCrashed(), &FetchError{URL: url, Err: err}
+	}
+	if err := options.wait.run(ctx, activity); err != nil {
+		return nil, isCrashed(), &FetchError{URL: url, Err: fmt.Errorf("waiting for page: %w", err)}
+	}
+	if err := runActions(ctx, activity, options.actions); err != nil {
+		return nil, isCrashed(), &FetchError{URL: url, Err: fmt.Errorf("running actions: %w", err)}
+	}
+	var body string
+	if err := chromedp.Run(ctx, chromedp.OuterHTML("html", &body)); err != nil {
+		return nil, isCrashed(), &FetchError{URL: url, Err: err}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	result := &FetchResult{Body: []byte(body), URL: url, Elapsed: time.Since(start)}
+	if document != nil {
+		result.StatusCode = int(document.Status)
+		result.URL = document.URL
+		result.ContentType = document.MimeType
+		result.Charset = document.Charset
+		result.Header = http.Header{}
+		for key, value := range document.Headers {
+			result.Header.Set(key, fmt.Sprint(value))
+		}
+	}
+	if result.StatusCode != 0 && (result.StatusCode < 200 || result.StatusCode > 299) {
+		return nil, crashed, &FetchError{URL: url, StatusCode: result.StatusCode}
+	}
+	return result, crashed, nil
+}
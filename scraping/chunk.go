@@ -0,0 +1,166 @@
+package scraping
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TokenCounter returns the number of tokens in a piece of text
+type TokenCounter func(text string) int
+
+// EstimateTokens is a rough token count of roughly four characters per token,
+// which is close enough for English text and HTML to budget requests
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// ChunkDocument splits the body of a document into HTML chunks of at most
+// maxTokens tokens each as measured by count. Splits are made between DOM
+// nodes where possible. Nodes which are too large on their own are split
+// into their children, and text which is too large is split between words.
+// Tables and lists which are split are reopened around each chunk so that
+// their rows and items keep their structure
+func ChunkDocument(doc *goquery.Document, maxTokens int, count TokenCounter) []string {
+	root := doc.Find("body")
+	if root.Length() == 0 {
+		root = doc.Selection
+	}
+	c := &chunker{maxTokens: maxTokens, count: count}
+	c.addNodes(root.Contents())
+	c.flush()
+	return c.chunks
+}
+
+// elements whose children are dropped or misread by the HTML parser outside
+// of them, so are reopened around each chunk when they are split
+var wrapperElements = map[atom.Atom]bool{
+	atom.Table: true, atom.Caption: true, atom.Thead: true, atom.Tbody: true,
+	atom.Tfoot: true, atom.Tr: true, atom.Td: true, atom.Th: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true,
+}
+
+// wrapper is an element being split whose tags are repeated in each chunk
+type wrapper struct {
+	open   string
+	close  string
+	tokens int
+}
+
+type chunker struct {
+	maxTokens int
+	count     TokenCounter
+	chunks    []string
+	current   strings.Builder
+	tokens    int
+	content   bool      // whether the current chunk holds anything besides wrappers
+	wrappers  []wrapper // elements being split, outermost first
+	opened    int       // wrappers opened in the current chunk
+}
+
+func (c *chunker) addNodes(nodes *goquery.Selection) {
+	nodes.Each(func(i int, s *goquery.Selection) {
+		html, err := goquery.OuterHtml(s)
+		if err != nil || strings.TrimSpace(html) == "" {
+			return
+		}
+		tokens := c.count(html)
+		switch {
+		case tokens <= c.maxTokens-c.wrapperTokens():
+			c.add(html, tokens)
+		case s.Children().Length() > 0:
+			if n := s.Get(0); wrapperElements[n.DataAtom] {
+				c.push(n)
+				c.addNodes(s.Contents())
+				c.pop()
+			} else {
+				c.addNodes(s.Contents())
+			}
+		default:
+			c.addText(s.Text())
+		}
+	})
+}
+
+// addText splits text which does not fit in a single chunk between words
+func (c *chunker) addText(text string) {
+	for _, word := range strings.Fields(text) {
+		word += " "
+		c.add(word, c.count(word))
+	}
+}
+
+func (c *chunker) add(html string, tokens int) {
+	if c.content && c.tokens+c.unopenedTokens()+tokens > c.maxTokens {
+		c.flush()
+	}
+	for _, w := range c.wrappers[c.opened:] {
+		c.current.WriteString(w.open)
+		c.tokens += w.tokens
+	}
+	c.opened = len(c.wrappers)
+	c.current.WriteString(html)
+	c.tokens += tokens
+	c.content = true
+}
+
+// push starts splitting an element into its children
+func (c *chunker) push(n *html.Node) {
+	var open strings.Builder
+	open.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		fmt.Fprintf(&open, ` %s="%s"`, a.Key, html.EscapeString(a.Val))
+	}
+	open.WriteString(">")
+	w := wrapper{open: open.String(), close: "</" + n.Data + ">"}
+	w.tokens = c.count(w.open + w.close)
+	c.wrappers = append(c.wrappers, w)
+}
+
+// pop finishes splitting the innermost element, closing it in the current
+// chunk if it was opened there
+func (c *chunker) pop() {
+	w := c.wrappers[len(c.wrappers)-1]
+	c.wrappers = c.wrappers[:len(c.wrappers)-1]
+	if c.opened > len(c.wrappers) {
+		c.current.WriteString(w.close)
+		c.opened--
+	}
+}
+
+// wrapperTokens returns the tokens taken by the tags of the elements being
+// split
+func (c *chunker) wrapperTokens() int {
+	tokens := 0
+	for _, w := range c.wrappers {
+		tokens += w.tokens
+	}
+	return tokens
+}
+
+// unopenedTokens returns the tokens of the wrappers still to be opened in the
+// current chunk
+func (c *chunker) unopenedTokens() int {
+	tokens := 0
+	for _, w := range c.wrappers[c.opened:] {
+		tokens += w.tokens
+	}
+	return tokens
+}
+
+func (c *chunker) flush() {
+	for i := c.opened - 1; i >= 0; i-- {
+		c.current.WriteString(c.wrappers[i].close)
+	}
+	if chunk := strings.TrimSpace(c.current.String()); chunk != "" && c.content {
+		c.chunks = append(c.chunks, chunk)
+	}
+	c.current.Reset()
+	c.tokens = 0
+	c.content = false
+	c.opened = 0
+}
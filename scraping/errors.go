@@ -0,0 +1,34 @@
+package scraping
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoMatch is returned by SelectContent when none of the selectors match,
+// which usually means the layout of the page has changed
+var ErrNoMatch = errors.New("selectors matched no elements")
+
+// ErrPoolClosed is returned when fetching with a BrowserPool which has been
+// closed
+var ErrPoolClosed = errors.New("browser pool is closed")
+
+// FetchError is returned when a page cannot be fetched. StatusCode is set when
+// the server responded with an error status and is zero for failures such as
+// network errors. Use errors.As to inspect it
+type FetchError struct {
+	URL        string
+	StatusCode int
+	Err        error // Underlying error, if any
+}
+
+func (e *FetchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("request for %s failed: %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("request for %s failed with status code %d", e.URL, e.StatusCode)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
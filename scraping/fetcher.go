@@ -0,0 +1,77 @@
+package scraping
+
+import (
+	"cmp"
+	"context"
+	"mime"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// FetchResult is a fetched page along with the metadata of the response. The
+// fetchers in this package convert the body to UTF-8
+type FetchResult struct {
+	Body        []byte
+	StatusCode  int           // Zero if the fetcher cannot report it
+	Header      http.Header   // Response headers, if known
+	URL         string        // Final URL after any redirects
+	ContentType string        // Media type without parameters, eg text/html
+	Charset     string        // Charset declared by the response, if any
+	Elapsed     time.Duration // Time taken to fetch the page
+}
+
+// Text returns the body of the page as a UTF-8 string. A body which is not
+// valid UTF-8 is decoded according to its charset, see DecodeHTML
+func (r *FetchResult) Text() string {
+	if utf8.Valid(r.Body) {
+		return string(r.Body)
+	}
+	contentType := cmp.Or(r.ContentType, "text/html")
+	if r.Charset != "" {
+		contentType = mime.FormatMediaType(contentType, map[string]string{"charset": r.Charset})
+	}
+	body, _, err := DecodeHTML(r.Body, contentType)
+	if err != nil {
+		return string(r.Body)
+	}
+	return string(body)
+}
+
+// Fetcher fetches a page. Implementations should return a *FetchError when
+// the server responds with an error status
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*FetchResult, error)
+}
+
+// FetcherFunc adapts a function to the Fetcher interface
+type FetcherFunc func(ctx context.Context, url string) (*FetchResult, error)
+
+// Fetch calls f
+func (f FetcherFunc) Fetch(ctx context.Context, url string) (*FetchResult, error) {
+	return f(ctx, url)
+}
+
+// FromFetchFunc adapts a function which returns only the body of a page to
+// the Fetcher interface. The result holds the body, the requested URL and
+// the time taken, as no other metadata is available
+func FromFetchFunc(f func(ctx context.Context, url string) (string, error)) Fetcher {
+	return FetcherFunc(func(ctx context.Context, url string) (*FetchResult, error) {
+		start := time.Now()
+		body, err := f(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		return &FetchResult{Body: []byte(body), URL: url, Elapsed: time.Since(start)}, nil
+	})
+}
+
+// parseContentType splits a Content-Type header into its media type and
+// charset
+func parseContentType(header string) (string, string) {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", ""
+	}
+	return mediaType, params["charset"]
+}
@@ -0,0 +1,374 @@
+package scraping
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToMarkdown converts HTML to compact Markdown. Headings, paragraphs,
+// lists, tables, links, emphasis, code and block quotes are kept while
+// attributes and other markup are dropped, which takes far fewer tokens to
+// send to a model than the HTML itself
+func HTMLToMarkdown(body string) (string, error) {
+	return convert(body, false)
+}
+
+// HTMLToText converts HTML to plain text, with blocks such as paragraphs,
+// list items and table rows on separate lines
+func HTMLToText(body string) (string, error) {
+	return convert(body, true)
+}
+
+func convert(body string, plain bool) (string, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	root := findElement(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	c := &converter{plain: plain}
+	return strings.Join(c.blocks(root), "\n\n"), nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// elements which start a new block of text
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Body: true, atom.Details: true, atom.Dd: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true,
+	atom.Html: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Summary: true,
+	atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Tfoot: true,
+	atom.Th: true, atom.Thead: true, atom.Tr: true, atom.Ul: true,
+}
+
+// elements whose content is never shown
+var hiddenElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Iframe: true,
+}
+
+type converter struct {
+	plain bool // plain text rather than Markdown
+}
+
+// blocks renders the children of a node as a list of blocks. Runs of inline
+// content between block elements become paragraphs
+func (c *converter) blocks(n *html.Node) []string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		if paragraph := cleanLines(inline.String()); paragraph != "" {
+			blocks = append(blocks, paragraph)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockElements[child.DataAtom] {
+			flush()
+			if block := c.block(child); block != "" {
+				blocks = append(blocks, block)
+			}
+			continue
+		}
+		inline.WriteString(c.inline(child))
+	}
+	flush()
+	return blocks
+}
+
+// block renders a block element
+func (c *converter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := singleLine(c.inlineChildren(n))
+		if text == "" || c.plain {
+			return text
+		}
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + text
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Table:
+		return c.table(n)
+	case atom.Pre:
+		text := strings.Trim(textContent(n), "\n")
+		if text == "" || c.plain {
+			return text
+		}
+		return "```\n" + text + "\n```"
+	case atom.Blockquote:
+		text := strings.Join(c.blocks(n), "\n\n")
+		if text == "" || c.plain {
+			return text
+		}
+		return prefixLines(text, "> ", "> ")
+	case atom.Hr:
+		if c.plain {
+			return ""
+		}
+		return "---"
+	case atom.Li:
+		// items outside of a list
+		text := strings.Join(c.blocks(n), "\n")
+		if text == "" || c.plain {
+			return text
+		}
+		return prefixLines(text, "- ", "  ")
+	}
+	return strings.Join(c.blocks(n), "\n\n")
+}
+
+// list renders the items of a list, indenting nested content under its item
+func (c *converter) list(n *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		text := strings.Join(c.blocks(child), "\n")
+		if child.DataAtom != atom.Li {
+			text = c.block(child)
+		}
+		if text == "" {
+			continue
+		}
+
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		if c.plain {
+			marker = ""
+		}
+		items = append(items, prefixLines(text, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table with its first row as the header
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	columns := 0
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type != html.ElementNode || child.DataAtom == atom.Table:
+				// nested tables are flattened into their cell
+			case child.DataAtom == atom.Tr:
+				if row := c.row(child); len(row) > 0 {
+					rows = append(rows, row)
+					columns = max(columns, len(row))
+				}
+			default:
+				collect(child)
+			}
+		}
+	}
+	collect(n)
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		if c.plain {
+			lines = append(lines, strings.Join(row, "\t"))
+			continue
+		}
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// row renders the cells of a table row
+func (c *converter) row(n *html.Node) []string {
+	var cells []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || (child.DataAtom != atom.Td && child.DataAtom != atom.Th) {
+			continue
+		}
+		cell := singleLine(c.inlineChildren(child))
+		if !c.plain {
+			cell = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// inline renders a node within a run of text. Whitespace is collapsed so
+// that only line breaks produce new lines
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return collapseSpace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if hiddenElements[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.A:
+		text := c.inlineChildren(n)
+		href := attr(n, "href")
+		if c.plain || strings.TrimSpace(text) == "" || href == "" ||
+			strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") || isInlineData(href) {
+			return text
+		}
+		return wrap(text, "[", "]("+href+")")
+	case atom.Strong, atom.B:
+		return c.emphasis(n, "**")
+	case atom.Em, atom.I:
+		return c.emphasis(n, "*")
+	case atom.Del, atom.S:
+		return c.emphasis(n, "~~")
+	case atom.Code:
+		return c.emphasis(n, "`")
+	case atom.Img:
+		alt := collapseSpace(attr(n, "alt"))
+		if c.plain || alt == "" || attr(n, "src") == "" || isInlineData(attr(n, "src")) {
+			return alt
+		}
+		return "![" + alt + "](" + attr(n, "src") + ")"
+	}
+
+	text := c.inlineChildren(n)
+	if blockElements[n.DataAtom] {
+		// a block inside an inline element, eg a div within a link
+		return " " + text + " "
+	}
+	return text
+}
+
+func (c *converter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+func (c *converter) emphasis(n *html.Node, marker string) string {
+	text := c.inlineChildren(n)
+	if c.plain || strings.TrimSpace(text) == "" {
+		return text
+	}
+	return wrap(text, marker, marker)
+}
+
+// wrap surrounds text with markers, keeping any surrounding whitespace outside
+// of them as Markdown requires
+func wrap(text string, open string, close string) string {
+	trimmed := strings.TrimSpace(text)
+	start := strings.Index(text, trimmed)
+	return text[:start] + open + trimmed + close + text[start+len(trimmed):]
+}
+
+// collapseSpace replaces each run of whitespace with a single space
+func collapseSpace(text string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// cleanLines trims each line of a paragraph and drops empty lines
+func cleanLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func singleLine(text string) string {
+	return strings.TrimSpace(collapseSpace(text))
+}
+
+// prefixLines prefixes the first line of text with first and the rest with
+// rest, leaving empty lines empty
+func prefixLines(text string, first string, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+			continue
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textContent returns the text of a node without collapsing whitespace
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package scraping
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Heuristics adapted from Mozilla's Readability. Class names and ids which
+// hint that an element is page chrome rather than content
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|consent|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|ad-break|agegate|pagination|pager|yom-remote`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveHints      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|product|text|blog|story`)
+	negativeHints      = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|cookie|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|widget`)
+)
+
+// elements which are never part of the main content
+const chromeSelector = `nav, aside, footer, form, dialog, iframe, script, style, noscript, ` +
+	`[role="navigation"], [role="banner"], [role="contentinfo"], [role="complementary"], ` +
+	`[role="dialog"], [role="alertdialog"], [aria-hidden="true"], [hidden]`
+
+// elements whose text is scored towards their ancestors
+const scoredSelector = "p, pre, td, section, h2, h3, h4, h5, h6, li"
+
+// minimum characters of text before an element is scored
+const minScoredText = 25
+
+// ExtractMainContent returns a copy of the document containing only its main
+// content, such as the body of an article or the product details on a shop
+// page. Navigation, headers, footers, sidebars, cookie banners and similar
+// page chrome are dropped.
+//
+// Elements are scored by the amount of text they contain, their link density
+// and hints from their class and id, as Mozilla's Readability does. If no
+// likely content is found a copy of the whole document is returned
+func ExtractMainContent(doc *goquery.Document) *goquery.Document {
+	docCopy := goquery.CloneDocument(doc)
+	body := docCopy.Find("body")
+	if body.Length() == 0 {
+		return docCopy
+	}
+
+	title := body.Find("h1")
+	pruned := goquery.CloneDocument(docCopy)
+	removeChrome(pruned.Find("body"))
+
+	top := topCandidate(pruned.Find("body"))
+	if top == nil {
+		return docCopy
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<html>" + baseHead(docCopy) + "<body>")
+	// keep the page heading when it sits outside of the content, as it often
+	// names what the content is about
+	if title.Length() == 1 && top.Find("h1").Length() == 0 {
+		if heading, err := goquery.OuterHtml(title); err == nil {
+			sb.WriteString(heading)
+		}
+	}
+	for _, node := range contentNodes(top) {
+		if html, err := goquery.OuterHtml(node); err == nil {
+			sb.WriteString(html)
+		}
+	}
+	sb.WriteString("</body></html>")
+
+	content, err := GoQueryDocFromBody(sb.String())
+	if err != nil {
+		return docCopy
+	}
+	return content
+}
+
+// removeChrome removes elements which are unlikely to be part of the content
+func removeChrome(body *goquery.Selection) {
+	body.Find(chromeSelector).Remove()
+	body.Find("*").Each(func(i int, s *goquery.Selection) {
+		if s.Is("body, article, main") || s.Closest("table").Length() > 0 {
+			return
+		}
+		hints := classAndID(s)
+		if hints != "" && unlikelyCandidates.MatchString(hints) && !maybeCandidate.MatchString(hints) {
+			s.Remove()
+		}
+	})
+}
+
+// topCandidate scores the ancestors of every element holding a reasonable
+// amount of text and returns the highest scoring one
+func topCandidate(body *goquery.Selection) *goquery.Selection {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node // in the order they were found, to break ties
+	candidate := func(s *goquery.Selection) *html.Node {
+		node := s.Get(0)
+		if _, ok := scores[node]; !ok {
+			scores[node] = baseScore(s)
+			candidates = append(candidates, node)
+		}
+		return node
+	}
+
+	body.Find(scoredSelector + ", div").Each(func(i int, s *goquery.Selection) {
+		// divs are only scored when they hold text directly rather than
+		// wrapping other blocks
+		if s.Is("div") && s.Children().Filter("div, p, section, article, ul, ol, table, pre, blockquote").Length() > 0 {
+			return
+		}
+		text := strings.TrimSpace(s.Text())
+		if len(text) < minScoredText {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		ancestor := s.Parent()
+		for level := 0; level < 5 && ancestor.Length() > 0 && !ancestor.Is("body, html"); level++ {
+			c := candidate(ancestor)
+			switch level {
+			case 0:
+				scores[c] += score
+			case 1:
+				scores[c] += score / 2
+			default:
+				scores[c] += score / float64(level*3)
+			}
+			ancestor = ancestor.Parent()
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, node := range candidates {
+		score := scores[node] * (1 - linkDensity(body.FindNodes(node)))
+		scores[node] = score
+		if score > topScore {
+			top, topScore = node, score
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	// a candidate which is most of its parent's content is better replaced by
+	// the parent, which is likely to hold related content such as a heading
+	for parent := top.Parent; parent != nil && parent.Data != "body" && parent.Data != "html"; parent = parent.Parent {
+		score, ok := scores[parent]
+		if !ok || score < topScore*0.75 {
+			break
+		}
+		top, topScore = parent, score
+	}
+	return body.FindNodes(top)
+}
+
+// contentNodes returns the top candidate along with any siblings which also
+// look like content, eg paragraphs split across several containers
+func contentNodes(top *goquery.Selection) []*goquery.Selection {
+	parent := top.Parent()
+	if parent.Length() == 0 || parent.Is("html") {
+		return []*goquery.Selection{top}
+	}
+
+	topText := len(strings.TrimSpace(top.Text()))
+	var nodes []*goquery.Selection
+	parent.Children().Each(func(i int, s *goquery.Selection) {
+		if s.Get(0) == top.Get(0) {
+			nodes = append(nodes, top)
+			return
+		}
+		text := strings.TrimSpace(s.Text())
+		density := linkDensity(s)
+		switch {
+		case len(text) >= max(topText/5, 80) && density < 0.25:
+			nodes = append(nodes, s)
+		case s.Is("p") && len(text) > 0 && density == 0 && strings.ContainsAny(text, ".!?"):
+			nodes = append(nodes, s)
+		}
+	})
+	return nodes
+}
+
+// baseScore is the starting score of an element from its tag and class hints
+func baseScore(s *goquery.Selection) float64 {
+	score := 0.0
+	switch goquery.NodeName(s) {
+	case "article", "main":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	hints := classAndID(s)
+	if positiveHints.MatchString(hints) {
+		score += 25
+	}
+	if negativeHints.MatchString(hints) {
+		score -= 25
+	}
+	return score
+}
+
+// linkDensity is the fraction of an element's text which is inside links
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(strings.TrimSpace(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+	linkLength := 0
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		linkLength += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+func classAndID(s *goquery.Selection) string {
+	class, _ := s.Attr("class")
+	id, _ := s.Attr("id")
+	return strings.TrimSpace(class + " " + id)
+}
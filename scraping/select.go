@@ -0,0 +1,130 @@
+package scraping
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// Selectors are CSS selectors, eg "#product-grid .item", unless they start
+// with "/" or "(" in which case they are XPath expressions, eg
+// "//div[@data-testid='price']"
+func isXPath(selector string) bool {
+	selector = strings.TrimSpace(selector)
+	return strings.HasPrefix(selector, "/") || strings.HasPrefix(selector, "(")
+}
+
+// ValidateSelector reports whether a CSS selector or XPath expression is
+// valid, so that mistakes are caught before a page is fetched
+func ValidateSelector(selector string) error {
+	var err error
+	if isXPath(selector) {
+		_, err = xpath.Compile(selector)
+	} else {
+		_, err = cascadia.ParseGroup(selector)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return nil
+}
+
+// findAll returns the elements of the document matching the selector
+func findAll(doc *goquery.Document, selector string) (*goquery.Selection, error) {
+	if err := ValidateSelector(selector); err != nil {
+		return nil, err
+	}
+	if !isXPath(selector) {
+		return doc.Find(selector), nil
+	}
+
+	var nodes []*html.Node
+	for _, root := range doc.Nodes {
+		found, err := htmlquery.QueryAll(root, selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+		for _, node := range found {
+			// text and attribute results are narrowed to their element
+			for node != nil && node.Type != html.ElementNode {
+				node = node.Parent
+			}
+			if node != nil {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return doc.FindNodes(nodes...), nil
+}
+
+// SelectContent returns a copy of the document whose body holds only the
+// elements matching any of the selectors, in document order. Elements nested
+// inside another match are only included once. It returns ErrNoMatch if
+// nothing matches
+func SelectContent(doc *goquery.Document, selectors ...string) (*goquery.Document, error) {
+	docCopy := goquery.CloneDocument(doc)
+	matched := map[*html.Node]bool{}
+	for _, selector := range selectors {
+		found, err := findAll(docCopy, selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range found.Nodes {
+			matched[node] = true
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoMatch, selectors)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<html>" + baseHead(docCopy) + "<body>")
+	docCopy.Find("*").Each(func(i int, s *goquery.Selection) {
+		if !matched[s.Get(0)] || hasMatchedAncestor(s.Get(0), matched) {
+			return
+		}
+		if html, err := goquery.OuterHtml(s); err == nil {
+			sb.WriteString(html)
+		}
+	})
+	sb.WriteString("</body></html>")
+	return GoQueryDocFromBody(sb.String())
+}
+
+// baseHead returns a head holding the <base> of a document, so that
+// documents rebuilt from parts of it resolve links in the same way
+func baseHead(doc *goquery.Document) string {
+	base, err := goquery.OuterHtml(doc.Find("head base[href]").First())
+	if err != nil || base == "" {
+		return ""
+	}
+	return "<head>" + base + "</head>"
+}
+
+func hasMatchedAncestor(node *html.Node, matched map[*html.Node]bool) bool {
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if matched[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveContent returns a copy of the document with the elements matching
+// any of the selectors removed
+func RemoveContent(doc *goquery.Document, selectors ...string) (*goquery.Document, error) {
+	docCopy := goquery.CloneDocument(doc)
+	for _, selector := range selectors {
+		found, err := findAll(docCopy, selector)
+		if err != nil {
+			return nil, err
+		}
+		found.Remove()
+	}
+	return docCopy, nil
+}
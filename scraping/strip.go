@@ -0,0 +1,147 @@
+package scraping
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// StripPolicy controls which elements and attributes survive stripping a
+// document before it is sent to a model
+type StripPolicy struct {
+	// Attributes which are kept, all others such as class, style and data
+	// attributes are dropped
+	Attributes []string
+	// Elements which are removed along with their content, as CSS selectors
+	RemoveElements []string
+	// Elements which are kept even though they contain no text, eg images
+	KeepEmpty []string
+	// URL which relative links and image sources are resolved against. A
+	// <base> element in the document takes precedence as it does in a browser
+	BaseURL string
+}
+
+// DefaultStripPolicy keeps links, image sources, alt text, titles, datetime
+// values and aria labels
+var DefaultStripPolicy = StripPolicy{
+	Attributes:     []string{"href", "src", "alt", "title", "datetime", "aria-label"},
+	RemoveElements: []string{"script", "style", "link", "meta", "noscript", "template", "svg"},
+	KeepEmpty:      []string{"img", "picture", "video", "audio", "source", "time", "br"},
+}
+
+// Validate reports whether the CSS selectors of RemoveElements and KeepEmpty
+// are valid, as StripWithPolicy would otherwise match nothing for them
+func (p StripPolicy) Validate() error {
+	for _, selector := range slices.Concat(p.RemoveElements, p.KeepEmpty) {
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return fmt.Errorf("invalid selector %q in strip policy: %w", selector, err)
+		}
+	}
+	return nil
+}
+
+// attributes holding a URL which are resolved against the base URL
+var urlAttributes = []string{"href", "src", "data-src", "poster", "action", "cite"}
+
+// attributes which describe an element without any text
+var labelAttributes = []string{"aria-label", "alt", "title"}
+
+// StripWithPolicy returns a copy of the document stripped according to the
+// policy. Elements with no text are removed unless the policy keeps them or
+// they carry a label such as aria-label, and attributes outside of the
+// allowlist are dropped, as are data and blob URLs which would hold inline
+// content such as base64 encoded images. Use Validate to check the selectors
+// of a policy before stripping with it
+func StripWithPolicy(doc *goquery.Document, policy StripPolicy) *goquery.Document {
+	docCopy := goquery.CloneDocument(doc)
+	base := baseURL(docCopy, policy.BaseURL)
+	if len(policy.RemoveElements) > 0 {
+		docCopy.Find(strings.Join(policy.RemoveElements, ", ")).Remove()
+	}
+
+	keep := slices.Clone(policy.KeepEmpty)
+	for _, attr := range labelAttributes {
+		if slices.Contains(policy.Attributes, attr) {
+			keep = append(keep, "["+attr+"]")
+		}
+	}
+	keepSelector := strings.Join(keep, ", ")
+
+	docCopy.Find("body *").Each(func(i int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) != "" {
+			return
+		}
+		if keepSelector != "" && (s.Is(keepSelector) || s.Find(keepSelector).Length() > 0) {
+			return
+		}
+		s.Remove()
+	})
+
+	docCopy.Find("*").Each(func(i int, s *goquery.Selection) {
+		node := s.Get(0)
+		kept := node.Attr[:0]
+		for _, attr := range node.Attr {
+			if !slices.Contains(policy.Attributes, attr.Key) {
+				continue
+			}
+			if slices.Contains(urlAttributes, attr.Key) {
+				// inline data can be far larger than the rest of the page
+				if isInlineData(attr.Val) {
+					continue
+				}
+				if base != nil {
+					attr.Val = resolveURL(base, attr.Val)
+				}
+			}
+			kept = append(kept, attr)
+		}
+		node.Attr = kept
+	})
+	return docCopy
+}
+
+// baseURL returns the URL relative links in the document are relative to, or
+// nil if it is not known
+func baseURL(doc *goquery.Document, pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil || pageURL == "" {
+		base = nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			if base == nil {
+				if ref.IsAbs() {
+					return ref
+				}
+				return nil
+			}
+			return base.ResolveReference(ref)
+		}
+	}
+	return base
+}
+
+// resolveURL resolves a link against the base URL, leaving it unchanged if
+// it cannot be parsed or is a fragment, data or javascript URL
+func resolveURL(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(link, "#") {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil || (ref.Scheme != "" && ref.Scheme != "http" && ref.Scheme != "https") {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// isInlineData reports whether a URL holds its content inline or refers to
+// data only available in the browser
+func isInlineData(link string) bool {
+	link = strings.ToLower(strings.TrimSpace(link))
+	return strings.HasPrefix(link, "data:") || strings.HasPrefix(link, "blob:")
+}
@@ -1,14 +1,15 @@
 // Package scraping contains utility functions for web scraping.
 
 // NOTE: the FetchFuncs here can be used directly as FetchFunc for the scrapai.Scrape
-// interface in a lot of simple cases. But for more complex instances where you want
-// granular control of headers, client timeouts etc you will probalby need to write
-// your own FectchFunc. In those cases these can be seen as useful examples.
+// interface in a lot of simple cases. For granular control of headers, proxies,
+// cookies, timeouts etc use NewHTTPFetcher, which can be passed to
+// scrapeai.WithFetcher.
 
 package scraping
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -21,61 +22,72 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/chromedp/chromedp"
 )
 
 // Simple fetch functionality that retrieves data from a given url or returns
-// the relevant err. Timeouts should be set on the context
+// the relevant err. Responses without a 2xx status return a *FetchError.
+// Timeouts should be set on the context
 func Fetch(ctx context.Context, url string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	result, err := FetchHTTP(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	return result.Text(), nil
+}
+
+// FetchHTTP fetches a url with a plain HTTP GET request using an HTTPFetcher
+// with the default settings, returning the body along with the response
+// metadata. Responses without a 2xx status return a *FetchError. Use
+// NewHTTPFetcher to set headers, proxies, cookies and limits
+func FetchHTTP(ctx context.Context, url string) (*FetchResult, error) {
+	fetcher, err := NewHTTPFetcher()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(body), nil
+	return fetcher.Fetch(ctx, url)
 }
 
 // Get body from chromedp headless browswer to collect dynamically rendered content
 func FetchFromChromedp(ctx context.Context, url string) (string, error) {
-	// Suppress chromedp's internal logging by using a no-op logger
-	chromedpCtx, cancel := chromedp.NewContext(
-		ctx,
-		chromedp.WithLogf(func(string, ...any) {}),
-	)
-	defer cancel()
-
-	var body string
-	err := chromedp.Run(chromedpCtx,
-		chromedp.Navigate(url),
-		chromedp.Sleep(2*time.Second), // Allow JS content to load
-		chromedp.OuterHTML("html", &body),
-	)
+	result, err := FetchChromedp(ctx, url)
 	if err != nil {
 		return "", err
 	}
-	return body, nil
+	return result.Text(), nil
+}
+
+// FetchChromedp renders a url in a headless browser with chromedp, returning
+// the rendered HTML along with the metadata of the document response. Pages
+// which load with an error status return a *FetchError. The page is captured
+// once DefaultWait is met. A new browser is launched for each call, use a
+// BrowserPool to reuse them
+func FetchChromedp(ctx context.Context, url string) (*FetchResult, error) {
+	return NewChromedpFetcher().Fetch(ctx, url)
 }
 
 // FetchWithZyteProxy fetches a URL using Zyte's proxy.
 // The ZYTE_API_KEY environment variable must be set.
 func FetchWithZyteProxy(ctx context.Context, targetURL string) (string, error) {
+	result, err := FetchZyteProxy(ctx, targetURL)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// FetchZyteProxy fetches a URL using Zyte's proxy, returning the body along
+// with the response metadata. Responses without a 200 status return a
+// *FetchError. The ZYTE_API_KEY environment variable must be set
+func FetchZyteProxy(ctx context.Context, targetURL string) (*FetchResult, error) {
+	start := time.Now()
 	apiKey := os.Getenv("ZYTE_API_KEY")
 	if apiKey == "" {
-		return "", fmt.Errorf("ZYTE_API_KEY is not set in the environment")
+		return nil, fmt.Errorf("ZYTE_API_KEY is not set in the environment")
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	// Parse proxy endpoint URL and embed API key in the URL for authentication
@@ -83,7 +95,7 @@ func FetchWithZyteProxy(ctx context.Context, targetURL string) (string, error) {
 	// The empty password after the colon matches curl's --proxy-user format
 	parsedProxy, err := url.Parse("http://api.zyte.com:8011")
 	if err != nil {
-		return "", fmt.Errorf("invalid proxy endpoint: %w", err)
+		return nil, fmt.Errorf("invalid proxy endpoint: %w", err)
 	}
 
 	// Set user info: API key as username, empty password
@@ -105,26 +117,40 @@ func FetchWithZyteProxy(ctx context.Context, targetURL string) (string, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("requesting through proxy: %w", err)
+		return nil, fmt.Errorf("requesting through proxy: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		err := fmt.Errorf(
-			"proxy request failed with status %d: %s",
-			resp.StatusCode,
-			body,
-		)
-		return "", err
+		return nil, &FetchError{
+			URL:        targetURL,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("proxy request failed with status %d: %s", resp.StatusCode, body),
+		}
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("reading response body: %w", err)
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	contentType, charset := parseContentType(resp.Header.Get("Content-Type"))
+	if isText(contentType) {
+		body, _, err = DecodeHTML(body, resp.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return string(body), nil
+	return &FetchResult{
+		Body:        body,
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		URL:         resp.Request.URL.String(),
+		ContentType: contentType,
+		Charset:     charset,
+		Elapsed:     time.Since(start),
+	}, nil
 }
 
 const ZyteExtractUrl string = "https://api.zyte.com/v1/extract"
@@ -134,12 +160,33 @@ type ZyteReqeust struct {
 	BrowserHTML bool   `json:"browserHtml"`
 }
 
+// zyteResponse is the part of the response from Zyte's extract API used for
+// browser rendered pages
+type zyteResponse struct {
+	Url         string `json:"url"`
+	StatusCode  int    `json:"statusCode"`
+	BrowserHTML string `json:"browserHtml"`
+}
+
 // FetchWithZyteProxy fetches a URL using Zyte's proxy with html rendering
 // The ZYTE_API_KEY environment variable must be set.
 func FetchWithZyteProxyHTML(ctx context.Context, targetURL string) (string, error) {
+	result, err := FetchZyteProxyHTML(ctx, targetURL)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// FetchZyteProxyHTML renders a URL in a browser with Zyte's extract API,
+// returning the rendered HTML along with the status code and final URL of the
+// page. Failed requests return a *FetchError. The ZYTE_API_KEY environment
+// variable must be set
+func FetchZyteProxyHTML(ctx context.Context, targetURL string) (*FetchResult, error) {
+	start := time.Now()
 	apiKey := os.Getenv("ZYTE_API_KEY")
 	if apiKey == "" {
-		return "", fmt.Errorf("ZYTE_API_KEY is not set in the environment")
+		return nil, fmt.Errorf("ZYTE_API_KEY is not set in the environment")
 	}
 
 	zReq := ZyteReqeust{Url: targetURL, BrowserHTML: true}
@@ -151,38 +198,47 @@ func FetchWithZyteProxyHTML(ctx context.Context, targetURL string) (string, erro
 		ZyteExtractUrl,
 		bytes.NewReader(jsonData),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.SetBasicAuth(apiKey, "")
 
-	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
-	}
-
 	client := http.Client{}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("requesting through proxy: %w", err)
+		return nil, fmt.Errorf("requesting through proxy: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		err := fmt.Errorf(
-			"proxy request failed with status %d: %s",
-			resp.StatusCode,
-			body,
-		)
-		return "", err
+		return nil, &FetchError{
+			URL:        targetURL,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("proxy request failed with status %d: %s", resp.StatusCode, body),
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("reading response body: %w", err)
+	var zResp zyteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&zResp); err != nil {
+		return nil, fmt.Errorf("decoding response body: %w", err)
+	}
+	// Zyte reports the status of the page itself in the response body
+	if zResp.StatusCode != 0 && (zResp.StatusCode < 200 || zResp.StatusCode > 299) {
+		return nil, &FetchError{URL: targetURL, StatusCode: zResp.StatusCode}
 	}
 
-	return string(body), nil
+	return &FetchResult{
+		Body:        []byte(zResp.BrowserHTML),
+		StatusCode:  zResp.StatusCode,
+		URL:         cmp.Or(zResp.Url, targetURL),
+		ContentType: "text/html",
+		Charset:     "utf-8",
+		Elapsed:     time.Since(start),
+	}, nil
 }
 
 // Takes an html body as a string and returns a goquery document
@@ -196,17 +252,10 @@ func GoQueryDocFromBody(body string) (*goquery.Document, error) {
 }
 
 // StripNonTextTags removes elements that don't contain text from a copy of the
-// given goquery document
+// given goquery document according to DefaultStripPolicy
 // Returns a new document with non-text elements removed
 func StripNonTextTags(doc *goquery.Document) *goquery.Document {
-	docCopy := goquery.CloneDocument(doc)
-	docCopy.Find("script, style, link, meta").Remove()
-	docCopy.Find("*").Each(func(i int, s *goquery.Selection) {
-		if strings.TrimSpace(s.Text()) == "" {
-			s.Remove()
-		}
-	})
-	return docCopy
+	return StripWithPolicy(doc, DefaultStripPolicy)
 }
 
 // Helper function to get the HTML string from a GoQuery document
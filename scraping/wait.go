@@ -0,0 +1,208 @@
+package scraping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// how often wait conditions check the page
+const pollInterval = 100 * time.Millisecond
+
+// DefaultWait waits up to 10 seconds for the network to be idle and the DOM
+// to stop changing for half a second, which suits most static pages and
+// single page apps alike
+var DefaultWait = WaitAtMost(10*time.Second,
+	WaitNetworkIdle(500*time.Millisecond),
+	WaitDOMQuiet(500*time.Millisecond),
+)
+
+// WaitCondition waits for a page rendered in a browser to be ready before its
+// HTML is captured. Conditions are composed with WaitAll, WaitAny and
+// WaitAtMost
+type WaitCondition struct {
+	wait func(ctx context.Context, activity *networkActivity) error
+}
+
+// run waits for the condition in a chromedp tab context
+func (c WaitCondition) run(ctx context.Context, activity *networkActivity) error {
+	if c.wait == nil {
+		return nil
+	}
+	return c.wait(ctx, activity)
+}
+
+// WaitVisible waits for an element matching a CSS selector or XPath expression
+// to be visible
+func WaitVisible(selector string) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		if err := chromedp.Run(ctx, chromedp.WaitVisible(selector, queryBy(selector))); err != nil {
+			return fmt.Errorf("waiting for %q to be visible: %w", selector, err)
+		}
+		return nil
+	}}
+}
+
+// WaitNetworkIdle waits until no requests have been in flight for the quiet
+// period
+func WaitNetworkIdle(quiet time.Duration) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		return poll(ctx, func() (bool, error) {
+			return activity.idleFor(quiet), nil
+		})
+	}}
+}
+
+// tracks when a mutation was last made to the DOM, returning the number of
+// milliseconds since then
+const domQuietScript = `(() => {
+	if (window.__scrapeaiLastMutation === undefined) {
+		window.__scrapeaiLastMutation = performance.now();
+		new MutationObserver(() => { window.__scrapeaiLastMutation = performance.now(); })
+			.observe(document, {subtree: true, childList: true, attributes: true, characterData: true});
+	}
+	return performance.now() - window.__scrapeaiLastMutation;
+})()`
+
+// WaitDOMQuiet waits until the DOM has not changed for the quiet period
+func WaitDOMQuiet(quiet time.Duration) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		return poll(ctx, func() (bool, error) {
+			var elapsed float64
+			if err := chromedp.Run(ctx, chromedp.Evaluate(domQuietScript, &elapsed)); err != nil {
+				return false, fmt.Errorf("watching the DOM: %w", err)
+			}
+			return time.Duration(elapsed*float64(time.Millisecond)) >= quiet, nil
+		})
+	}}
+}
+
+// WaitJS waits until a JavaScript expression is truthy, eg
+// "document.querySelectorAll('.product').length >= 20"
+func WaitJS(expression string) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		return poll(ctx, func() (bool, error) {
+			var ok bool
+			if err := chromedp.Run(ctx, chromedp.Evaluate("!!("+expression+")", &ok)); err != nil {
+				return false, fmt.Errorf("evaluating %q: %w", expression, err)
+			}
+			return ok, nil
+		})
+	}}
+}
+
+// WaitDuration waits for a fixed amount of time
+func WaitDuration(d time.Duration) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		return chromedp.Run(ctx, chromedp.Sleep(d))
+	}}
+}
+
+// WaitAll waits for each of the conditions in turn
+func WaitAll(conditions ...WaitCondition) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		for _, condition := range conditions {
+			if err := condition.run(ctx, activity); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// WaitAny waits until one of the conditions is met
+func WaitAny(conditions ...WaitCondition) WaitCondition {
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		if len(conditions) == 0 {
+			return nil
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		results := make(chan error, len(conditions))
+		for _, condition := range conditions {
+			go func() {
+				results <- condition.run(ctx, activity)
+			}()
+		}
+		var errs []error
+		for range conditions {
+			err := <-results
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}}
+}
+
+// WaitAtMost waits for all of the conditions for up to the timeout, after
+// which the page is captured as it is rather than failing
+func WaitAtMost(timeout time.Duration, conditions ...WaitCondition) WaitCondition {
+	condition := WaitAll(conditions...)
+	return WaitCondition{func(ctx context.Context, activity *networkActivity) error {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := condition.run(waitCtx, activity)
+		if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+			return nil
+		}
+		return err
+	}}
+}
+
+// poll calls check until it reports the condition is met
+func poll(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		ok, err := check()
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// networkActivity tracks the requests made by a page
+type networkActivity struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	last     time.Time // when a request last started or finished
+}
+
+func newNetworkActivity() *networkActivity {
+	return &networkActivity{inflight: map[network.RequestID]bool{}, last: time.Now()}
+}
+
+// record updates the activity from a network event
+func (a *networkActivity) record(ev any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		a.inflight[ev.RequestID] = true
+	case *network.EventLoadingFinished:
+		delete(a.inflight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(a.inflight, ev.RequestID)
+	default:
+		return
+	}
+	a.last = time.Now()
+}
+
+func (a *networkActivity) idleFor(quiet time.Duration) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.inflight) == 0 && time.Since(a.last) >= quiet
+}
@@ -0,0 +1,200 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/anthropic"
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+const toolResponse = `{
+	"id": "msg_123",
+	"type": "message",
+	"role": "assistant",
+	"model": "claude-haiku-4-5",
+	"content": [
+		{"type": "text", "text": "Recording the result"},
+		{"type": "tool_use", "id": "toolu_1", "name": "scrape_result", "input": {"data": ["Example Domain"]}}
+	],
+	"stop_reason": "tool_use",
+	"usage": {"input_tokens": 120, "output_tokens": 15}
+}`
+
+func newAnthropicServer(t *testing.T, status int, body string, got *anthropic.MessageRequest) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("Expected x-api-key header to be set, got %q", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Errorf("Expected anthropic-version header to be set")
+		}
+		if got != nil {
+			if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+				t.Errorf("Error decoding request: %v", err)
+			}
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExtract(t *testing.T) {
+	var got anthropic.MessageRequest
+	server := newAnthropicServer(t, http.StatusOK, toolResponse, &got)
+	provider := anthropic.NewProvider(anthropic.WithApiUrl(server.URL), anthropic.WithApiKey("test-key"))
+
+	response, err := provider.Extract(context.Background(), &llm.Request{
+		Prompt: "Extract the main headline",
+		Page:   "<h1>Example Domain</h1>",
+		Schema: gpt.DefaultSchemaTemplate,
+	})
+	if err != nil {
+		t.Fatalf("Error extracting: %v", err)
+	}
+
+	if response.Content != `{"data": ["Example Domain"]}` {
+		t.Errorf("Unexpected content: %s", response.Content)
+	}
+	if response.Usage.PromptTokens != 120 || response.Usage.CompletionTokens != 15 || response.Usage.TotalTokens != 135 {
+		t.Errorf("Unexpected usage: %+v", response.Usage)
+	}
+
+	if len(got.Tools) != 1 || got.Tools[0].Name != "scrape_result" {
+		t.Fatalf("Expected a single scrape_result tool, got %+v", got.Tools)
+	}
+	if got.ToolChoice.Type != "tool" || got.ToolChoice.Name != "scrape_result" {
+		t.Errorf("Expected the tool call to be forced, got %+v", got.ToolChoice)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(got.Tools[0].InputSchema, &schema); err != nil || schema["type"] != "object" {
+		t.Errorf("Expected the schema to be sent as the tool input schema, got %s", got.Tools[0].InputSchema)
+	}
+	if !strings.HasPrefix(got.Messages[0].Content, "Extract the main headline\n\n") {
+		t.Errorf("Unexpected message content: %q", got.Messages[0].Content)
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		inError string
+	}{
+		{
+			name:    "error status",
+			status:  http.StatusBadRequest,
+			body:    `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`,
+			inError: "status code: 400",
+		},
+		{
+			name:    "no tool call",
+			status:  http.StatusOK,
+			body:    `{"content":[{"type":"text","text":"I cannot help"}],"stop_reason":"end_turn"}`,
+			inError: "did not contain a scrape_result tool call",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAnthropicServer(t, tt.status, tt.body, nil)
+			provider := anthropic.NewProvider(anthropic.WithApiUrl(server.URL), anthropic.WithApiKey("test-key"))
+			_, err := provider.Extract(context.Background(), &llm.Request{Schema: gpt.DefaultSchemaTemplate})
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected error containing '%s' but got '%s'", tt.inError, err.Error())
+			}
+		})
+	}
+}
+
+func TestExtractAPIError(t *testing.T) {
+	server := newAnthropicServer(t, http.StatusTooManyRequests,
+		`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`, nil)
+	provider := anthropic.NewProvider(anthropic.WithApiUrl(server.URL), anthropic.WithApiKey("test-key"))
+
+	_, err := provider.Extract(context.Background(), &llm.Request{Schema: gpt.DefaultSchemaTemplate})
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+	if apiErr.Provider != "Anthropic" || apiErr.StatusCode != http.StatusTooManyRequests ||
+		apiErr.Type != "rate_limit_error" || apiErr.Message != "slow down" {
+		t.Errorf("Unexpected APIError fields: %+v", apiErr)
+	}
+}
+
+func TestExtractStopReasons(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		refusal   string
+		truncated bool
+	}{
+		{
+			name:    "refusal",
+			body:    `{"content":[{"type":"text","text":"I can't help with that"}],"stop_reason":"refusal"}`,
+			refusal: "I can't help with that",
+		},
+		{
+			name:      "max tokens",
+			body:      `{"content":[{"type":"text","text":"Working on it"}],"stop_reason":"max_tokens"}`,
+			truncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAnthropicServer(t, http.StatusOK, tt.body, nil)
+			provider := anthropic.NewProvider(anthropic.WithApiUrl(server.URL), anthropic.WithApiKey("test-key"))
+			response, err := provider.Extract(context.Background(), &llm.Request{Schema: gpt.DefaultSchemaTemplate})
+			if err != nil {
+				t.Fatalf("Error extracting: %v", err)
+			}
+			if response.Refusal != tt.refusal || response.Truncated() != tt.truncated {
+				t.Errorf("Unexpected response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestScrapeWithAnthropic(t *testing.T) {
+	api := newAnthropicServer(t, http.StatusOK, toolResponse, nil)
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><h1>Example Domain</h1></body></html>")
+	}))
+	defer page.Close()
+
+	req, err := scrapeai.NewScrapeAiRequest(page.URL, "Extract the main headline",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(anthropic.NewProvider(
+			anthropic.WithApiUrl(api.URL),
+			anthropic.WithApiKey("test-key"),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	result, err := scrapeai.Scrape(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	if !strings.Contains(result.Results, "Example Domain") {
+		t.Errorf("Unexpected results: %s", result.Results)
+	}
+}
@@ -0,0 +1,100 @@
+package gpt_test
+
+import (
+	"testing"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/gpt/schema"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   *schema.Schema
+		expected string
+	}{
+		{
+			name: "properties",
+			schema: schema.Object().
+				Prop("name", schema.String().Desc("Name of the product")).
+				Prop("price", schema.Number().Minimum(0)).
+				Prop("quantity", schema.Integer().Nullable()).
+				Prop("in_stock", schema.Boolean()),
+			expected: `{"type":"object","properties":{` +
+				`"name":{"type":"string","description":"Name of the product"},` +
+				`"price":{"type":"number","minimum":0},` +
+				`"quantity":{"type":["integer","null"]},` +
+				`"in_stock":{"type":"boolean"}` +
+				`},"additionalProperties":false,"required":["name","price","quantity","in_stock"]}`,
+		},
+		{
+			name:     "empty object",
+			schema:   schema.Object(),
+			expected: `{"type":"object","properties":{},"additionalProperties":false,"required":[]}`,
+		},
+		{
+			name: "replaced property",
+			schema: schema.Object().
+				Prop("name", schema.String()).
+				Prop("name", schema.String().Format("email")),
+			expected: `{"type":"object","properties":{"name":{"type":"string","format":"email"}},` +
+				`"additionalProperties":false,"required":["name"]}`,
+		},
+		{
+			name: "enums",
+			schema: schema.Object().
+				Prop("currency", schema.String().Enum("USD", "EUR")).
+				Prop("rating", schema.Integer().Enum(1, 2, 3).Nullable()).
+				Prop("size", schema.String().Nullable().Enum("S", "M")),
+			expected: `{"type":"object","properties":{` +
+				`"currency":{"type":"string","enum":["USD","EUR"]},` +
+				`"rating":{"type":["integer","null"],"enum":[1,2,3,null]},` +
+				`"size":{"type":["string","null"],"enum":["S","M",null]}` +
+				`},"additionalProperties":false,"required":["currency","rating","size"]}`,
+		},
+		{
+			name: "arrays",
+			schema: schema.Object().
+				Prop("tags", schema.Array(schema.String()).MinItems(1).MaxItems(5)).
+				Prop("prices", schema.Array(schema.Object().Prop("amount", schema.Number())).Nullable()),
+			expected: `{"type":"object","properties":{` +
+				`"tags":{"type":"array","items":{"type":"string"},"minItems":1,"maxItems":5},` +
+				`"prices":{"anyOf":[{"type":"array","items":{"type":"object","properties":{"amount":{"type":"number"}},` +
+				`"additionalProperties":false,"required":["amount"]}},{"type":"null"}]}` +
+				`},"additionalProperties":false,"required":["tags","prices"]}`,
+		},
+		{
+			name: "refs",
+			schema: schema.Object().
+				Prop("root", schema.Ref("Category")).
+				Def("Category", schema.Object().
+					Prop("name", schema.String()).
+					Prop("children", schema.Array(schema.Ref("Category")))),
+			expected: `{"type":"object","properties":{"root":{"$ref":"#/$defs/Category"}},` +
+				`"additionalProperties":false,"required":["root"],"$defs":{"Category":{"type":"object","properties":{` +
+				`"name":{"type":"string"},"children":{"type":"array","items":{"$ref":"#/$defs/Category"}}` +
+				`},"additionalProperties":false,"required":["name","children"]}}}`,
+		},
+		{
+			name: "root ref",
+			schema: schema.Object().
+				Prop("name", schema.String()).
+				Prop("parent", schema.AnyOf(schema.Ref(""), schema.Object().Prop("id", schema.String()))),
+			expected: `{"type":"object","properties":{"name":{"type":"string"},` +
+				`"parent":{"anyOf":[{"$ref":"#"},{"type":"object","properties":{"id":{"type":"string"}},` +
+				`"additionalProperties":false,"required":["id"]}]}},` +
+				`"additionalProperties":false,"required":["name","parent"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.schema.String() != tt.expected {
+				t.Errorf("Unexpected schema\nexpected: %s\n     got: %s", tt.expected, tt.schema.String())
+			}
+			if err := gpt.ValidateSchema(tt.schema.String()); err != nil {
+				t.Errorf("Built schema is not strict mode compliant: %v", err)
+			}
+		})
+	}
+}
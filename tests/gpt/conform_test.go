@@ -0,0 +1,173 @@
+package gpt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/gpt"
+)
+
+const productSchema = `{
+	"type": "object",
+	"properties": {
+		"products": {
+			"type": "array",
+			"items": {"$ref": "#/$defs/product"}
+		}
+	},
+	"additionalProperties": false,
+	"required": ["products"],
+	"$defs": {
+		"product": {
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"price": {"type": "number"},
+				"stock": {"type": "integer"},
+				"sale": {"type": ["boolean", "null"]},
+				"currency": {"type": "string", "enum": ["USD", "EUR"]},
+				"sku": {"anyOf": [{"type": "string"}, {"type": "null"}]}
+			},
+			"additionalProperties": false,
+			"required": ["name", "price", "stock", "sale", "currency", "sku"]
+		}
+	}
+}`
+
+const constrainedSchema = `{
+	"type": "object",
+	"properties": {
+		"code": {"type": "string", "pattern": "^[A-Z]{3}-\\d+$"},
+		"email": {"type": "string", "format": "email"},
+		"updated": {"type": "string", "format": "date-time"},
+		"rating": {"type": "number", "minimum": 1, "maximum": 5},
+		"quantity": {"type": "integer", "exclusiveMinimum": 0, "multipleOf": 2},
+		"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2}
+	},
+	"additionalProperties": false,
+	"required": ["code", "email", "updated", "rating", "quantity", "tags"]
+}`
+
+func TestValidateResponseConstraints(t *testing.T) {
+	valid := map[string]any{
+		"code":     `"ABC-123"`,
+		"email":    `"sam@example.com"`,
+		"updated":  `"2024-07-18T10:00:00Z"`,
+		"rating":   `4.5`,
+		"quantity": `4`,
+		"tags":     `["a"]`,
+	}
+	build := func(key, value string) string {
+		parts := []string{}
+		for _, k := range []string{"code", "email", "updated", "rating", "quantity", "tags"} {
+			v := valid[k].(string)
+			if k == key {
+				v = value
+			}
+			parts = append(parts, `"`+k+`":`+v)
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	}
+
+	if err := gpt.ValidateResponse(constrainedSchema, build("", "")); err != nil {
+		t.Fatalf("Expected valid response, got %v", err)
+	}
+
+	tests := []struct {
+		key     string
+		value   string
+		inError string
+	}{
+		{"code", `"abc-123"`, `/code: value "abc-123" does not match the pattern`},
+		{"email", `"not an email"`, `/email: value "not an email" is not a valid email`},
+		{"updated", `"yesterday"`, `/updated: value "yesterday" is not a valid date-time`},
+		{"rating", `0.5`, `/rating: value 0.5 is less than the minimum 1`},
+		{"rating", `6`, `/rating: value 6 is greater than the maximum 5`},
+		{"quantity", `0`, `/quantity: value 0 must be greater than 0`},
+		{"quantity", `3`, `/quantity: value 3 is not a multiple of 2`},
+		{"tags", `[]`, `/tags: expected at least 1 items but got 0`},
+		{"tags", `["a","b","c"]`, `/tags: expected at most 2 items but got 3`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.value, func(t *testing.T) {
+			err := gpt.ValidateResponse(constrainedSchema, build(tt.key, tt.value))
+			if err == nil {
+				t.Fatalf("Expected error containing '%s'", tt.inError)
+			}
+			if !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected error containing '%s' but got '%s'", tt.inError, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		inError string
+	}{
+		{
+			name:    "valid",
+			content: `{"products":[{"name":"Hat","price":9.5,"stock":3,"sale":null,"currency":"USD","sku":"H1"}]}`,
+		},
+		{
+			name:    "integer accepted as number",
+			content: `{"products":[{"name":"Hat","price":9,"stock":3,"sale":true,"currency":"EUR","sku":null}]}`,
+		},
+		{
+			name:    "invalid json",
+			content: `{"products":`,
+			inError: "response is not valid json",
+		},
+		{
+			name:    "wrong type",
+			content: `{"products":[{"name":"Hat","price":"9.50","stock":3,"sale":null,"currency":"USD","sku":null}]}`,
+			inError: "/products/0/price: expected number but got string",
+		},
+		{
+			name:    "fractional integer",
+			content: `{"products":[{"name":"Hat","price":9.5,"stock":3.5,"sale":null,"currency":"USD","sku":null}]}`,
+			inError: "/products/0/stock: expected integer but got number",
+		},
+		{
+			name:    "missing required",
+			content: `{"products":[{"name":"Hat","price":9.5,"stock":3,"sale":null,"currency":"USD"}]}`,
+			inError: "/products/0: missing required property sku",
+		},
+		{
+			name:    "additional property",
+			content: `{"products":[],"extra":1}`,
+			inError: "/: unexpected property extra",
+		},
+		{
+			name:    "enum",
+			content: `{"products":[{"name":"Hat","price":9.5,"stock":3,"sale":null,"currency":"GBP","sku":null}]}`,
+			inError: "/products/0/currency: value GBP is not one of the allowed enum values",
+		},
+		{
+			name:    "anyOf",
+			content: `{"products":[{"name":"Hat","price":9.5,"stock":3,"sale":null,"currency":"USD","sku":12}]}`,
+			inError: "/products/0/sku: value does not match any of the anyOf schemas",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gpt.ValidateResponse(productSchema, tt.content)
+			if tt.inError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing '%s'", tt.inError)
+			}
+			if !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected error containing '%s' but got '%s'", tt.inError, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package gpt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/llm"
+)
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("cancels request in flight", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+		provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := provider.Extract(ctx, &llm.Request{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected request to be aborted promptly, took %v", elapsed)
+		}
+	})
+
+	t.Run("cancels wait between retries", func(t *testing.T) {
+		server, _ := newFlakyServer(t, 10, func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+		provider := gpt.NewProvider(
+			gpt.WithBaseUrl(server.URL),
+			gpt.WithRetryPolicy(gpt.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute}),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := provider.Extract(ctx, &llm.Request{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected retry wait to be aborted promptly, took %v", elapsed)
+		}
+	})
+}
+
+// countingTransport counts the requests made through it
+type countingTransport struct {
+	count int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.count++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, chatResponse(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	provider := gpt.NewProvider(
+		gpt.WithBaseUrl(server.URL),
+		gpt.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	if _, err := provider.Extract(context.Background(), &llm.Request{}); err != nil {
+		t.Fatalf("Error extracting: %v", err)
+	}
+	if transport.count != 1 {
+		t.Errorf("Expected the request to use the injected client, got %d requests", transport.count)
+	}
+}
@@ -0,0 +1,308 @@
+package gpt_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/llm"
+)
+
+// chatResponse wraps content in a minimal chat completions response
+func chatResponse(content string) string {
+	encoded, _ := json.Marshal(content)
+	return fmt.Sprintf(`{
+		"id": "chatcmpl-123",
+		"model": "llama3.1",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": %s}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+	}`, encoded)
+}
+
+// newChatServer returns a server which records the decoded request body and
+// headers and replies with the given content
+func newChatServer(t *testing.T, content string, got *map[string]any, headers *http.Header) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got != nil {
+			if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+				t.Errorf("Error decoding request: %v", err)
+			}
+		}
+		if headers != nil {
+			*headers = r.Header.Clone()
+		}
+		fmt.Fprint(w, chatResponse(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProviderSchemaModes(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           gpt.SchemaMode
+		field          string
+		absent         []string
+		schemaInPrompt bool
+		formatType     string
+		strict         bool
+	}{
+		{name: "json schema", mode: gpt.SchemaModeJSONSchema, field: "response_format", absent: []string{"format", "guided_json"}, formatType: "json_schema", strict: true},
+		{name: "json object", mode: gpt.SchemaModeJSONObject, field: "response_format", absent: []string{"format", "guided_json"}, schemaInPrompt: true, formatType: "json_object"},
+		{name: "format", mode: gpt.SchemaModeFormat, field: "response_format", absent: []string{"format", "guided_json"}, schemaInPrompt: true, formatType: "json_schema"},
+		{name: "guided json", mode: gpt.SchemaModeGuidedJSON, field: "guided_json", absent: []string{"response_format", "format"}, schemaInPrompt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := newChatServer(t, `{"data":["Example Domain"]}`, &got, nil)
+			provider := gpt.NewProvider(
+				gpt.WithBaseUrl(server.URL+"/v1/"),
+				gpt.WithModel("llama3.1"),
+				gpt.WithSchemaMode(tt.mode),
+			)
+
+			response, err := provider.Extract(context.Background(), &llm.Request{
+				Prompt: "Extract the main headline",
+				Page:   "<h1>Example Domain</h1>",
+				Schema: gpt.DefaultSchemaTemplate,
+			})
+			if err != nil {
+				t.Fatalf("Error extracting: %v", err)
+			}
+			if response.Usage.TotalTokens != 15 {
+				t.Errorf("Expected usage to be reported, got %+v", response.Usage)
+			}
+
+			if got["model"] != "llama3.1" {
+				t.Errorf("Expected model llama3.1, got %v", got["model"])
+			}
+			if _, ok := got[tt.field]; !ok {
+				t.Errorf("Expected request to contain %s", tt.field)
+			}
+			for _, field := range tt.absent {
+				if _, ok := got[field]; ok {
+					t.Errorf("Expected request not to contain %s", field)
+				}
+			}
+			if tt.formatType != "" {
+				format, _ := got["response_format"].(map[string]any)
+				if format["type"] != tt.formatType {
+					t.Errorf("Expected response_format type %s, got %v", tt.formatType, format["type"])
+				}
+				if tt.formatType == "json_schema" {
+					jsonSchema, _ := format["json_schema"].(map[string]any)
+					if jsonSchema["schema"] == nil {
+						t.Errorf("Expected response_format to contain the schema, got %v", format)
+					}
+					if jsonSchema["strict"] != tt.strict {
+						t.Errorf("Expected strict to be %v, got %v", tt.strict, jsonSchema["strict"])
+					}
+				}
+			}
+			content := got["messages"].([]any)[0].(map[string]any)["content"].(string)
+			if strings.Contains(content, `"additionalProperties"`) != tt.schemaInPrompt {
+				t.Errorf("Expected schema in prompt to be %v, prompt was %q", tt.schemaInPrompt, content)
+			}
+		})
+	}
+}
+
+func TestProviderResponseValidation(t *testing.T) {
+	server := newChatServer(t, `{"data":"not a list"}`, nil, nil)
+
+	t.Run("validation rejects mismatched responses", func(t *testing.T) {
+		provider := gpt.NewProvider(
+			gpt.WithBaseUrl(server.URL+"/v1"),
+			gpt.WithSchemaMode(gpt.SchemaModeFormat),
+			gpt.WithResponseValidation(),
+		)
+		_, err := provider.Extract(context.Background(), &llm.Request{Schema: gpt.DefaultSchemaTemplate})
+		if err == nil || !strings.Contains(err.Error(), "response does not match schema") {
+			t.Errorf("Expected schema mismatch error, got %v", err)
+		}
+	})
+
+	t.Run("responses are returned unchecked by default", func(t *testing.T) {
+		provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL+"/v1"), gpt.WithSchemaMode(gpt.SchemaModeFormat))
+		response, err := provider.Extract(context.Background(), &llm.Request{Schema: gpt.DefaultSchemaTemplate})
+		if err != nil {
+			t.Fatalf("Expected no error without validation, got %v", err)
+		}
+		if response.Content != `{"data":"not a list"}` {
+			t.Errorf("Expected the raw content, got %q", response.Content)
+		}
+	})
+}
+
+func TestProviderAuthHeaders(t *testing.T) {
+	t.Run("no auth by default for custom servers", func(t *testing.T) {
+		var headers http.Header
+		server := newChatServer(t, `{"data":[]}`, nil, &headers)
+		provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL + "/v1"))
+		if _, err := provider.Extract(context.Background(), &llm.Request{}); err != nil {
+			t.Fatalf("Error extracting: %v", err)
+		}
+		if headers.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header, got %q", headers.Get("Authorization"))
+		}
+	})
+
+	t.Run("custom auth header", func(t *testing.T) {
+		var headers http.Header
+		server := newChatServer(t, `{"data":[]}`, nil, &headers)
+		provider := gpt.NewProvider(
+			gpt.WithBaseUrl(server.URL+"/v1"),
+			gpt.WithAuthHeader("X-Api-Key", "secret"),
+		)
+		if _, err := provider.Extract(context.Background(), &llm.Request{}); err != nil {
+			t.Fatalf("Error extracting: %v", err)
+		}
+		if headers.Get("X-Api-Key") != "secret" {
+			t.Errorf("Expected X-Api-Key header, got %q", headers.Get("X-Api-Key"))
+		}
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		var headers http.Header
+		server := newChatServer(t, `{"data":[]}`, nil, &headers)
+		provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL+"/v1"), gpt.WithApiKey("secret"))
+		if _, err := provider.Extract(context.Background(), &llm.Request{}); err != nil {
+			t.Fatalf("Error extracting: %v", err)
+		}
+		if headers.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", headers.Get("Authorization"))
+		}
+	})
+}
+
+func TestProviderResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		maxTokens int
+		refusal   string
+		truncated bool
+		inError   string
+	}{
+		{
+			name:    "refusal",
+			body:    `{"choices":[{"message":{"role":"assistant","content":"","refusal":"I can't help with that"},"finish_reason":"stop"}]}`,
+			refusal: "I can't help with that",
+		},
+		{
+			name:      "truncated",
+			body:      `{"choices":[{"message":{"role":"assistant","content":"{\"data\":["},"finish_reason":"length"}]}`,
+			maxTokens: 512,
+			truncated: true,
+		},
+		{
+			name:    "no choices",
+			body:    `{"id":"chatcmpl-1","choices":[]}`,
+			inError: "response chatcmpl-1 contained no choices",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("Error decoding request: %v", err)
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			t.Cleanup(server.Close)
+
+			provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL))
+			response, err := provider.Extract(context.Background(), &llm.Request{
+				Prompt:    "Extract the main headline",
+				MaxTokens: tt.maxTokens,
+			})
+			if tt.inError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected error containing '%s', got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error extracting: %v", err)
+			}
+			if response.Refusal != tt.refusal || response.Truncated() != tt.truncated {
+				t.Errorf("Unexpected response: %+v", response)
+			}
+			if maxTokens, _ := got["max_tokens"].(float64); int(maxTokens) != tt.maxTokens {
+				t.Errorf("Expected max_tokens %d, got %v", tt.maxTokens, got["max_tokens"])
+			}
+		})
+	}
+}
+
+// redirectTransport sends every request to a test server in place of the
+// host it was made to
+type redirectTransport struct {
+	target string
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = "http"
+	r.URL.Host = strings.TrimPrefix(rt.target, "http://")
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestProviderOutputLimit(t *testing.T) {
+	tests := []struct {
+		name            string
+		model           string
+		openai          bool
+		wantParam       string
+		omitTemperature bool
+	}{
+		{name: "compatible server", model: "llama3.1", wantParam: "max_tokens"},
+		{name: "openai", model: "gpt-4o-mini", openai: true, wantParam: "max_completion_tokens"},
+		{name: "openai reasoning model", model: "o3-mini", openai: true, wantParam: "max_completion_tokens", omitTemperature: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			server := newChatServer(t, `{"data":[]}`, &got, nil)
+
+			options := []gpt.Option{gpt.WithModel(tt.model)}
+			if tt.openai {
+				options = append(options, gpt.WithApiKey("test-key"),
+					gpt.WithHTTPClient(&http.Client{Transport: redirectTransport{server.URL}}))
+			} else {
+				options = append(options, gpt.WithBaseUrl(server.URL+"/v1"))
+			}
+			provider := gpt.NewProvider(options...)
+			if _, err := provider.Extract(context.Background(), &llm.Request{Prompt: "Extract the products", MaxTokens: 512}); err != nil {
+				t.Fatalf("Error extracting: %v", err)
+			}
+
+			for _, param := range []string{"max_tokens", "max_completion_tokens"} {
+				limit, _ := got[param].(float64)
+				if param == tt.wantParam && limit != 512 {
+					t.Errorf("Expected %s of 512, got %v", param, got[param])
+				}
+				if param != tt.wantParam && got[param] != nil {
+					t.Errorf("Expected no %s, got %v", param, got[param])
+				}
+			}
+			if _, ok := got["temperature"]; ok == tt.omitTemperature {
+				t.Errorf("Expected temperature sent to be %v, got %v", !tt.omitTemperature, got["temperature"])
+			}
+		})
+	}
+}
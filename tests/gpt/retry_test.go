@@ -0,0 +1,132 @@
+package gpt_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/llm"
+)
+
+var fastRetries = gpt.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+// newFlakyServer fails the first failures requests using fail and then
+// succeeds, counting every request it receives
+func newFlakyServer(t *testing.T, failures int32, fail func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			fail(w)
+			return
+		}
+		fmt.Fprint(w, chatResponse(`{"data":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		fail      func(w http.ResponseWriter)
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			name:     "rate limit then success",
+			failures: 2,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("retry-after-ms", "5")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantCalls: 3,
+		},
+		{
+			name:      "server error then success",
+			failures:  1,
+			fail:      func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+			wantCalls: 2,
+		},
+		{
+			name:      "retries exhausted",
+			failures:  10,
+			fail:      func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) },
+			wantCalls: 4,
+			wantErr:   true,
+		},
+		{
+			name:      "bad request is permanent",
+			failures:  10,
+			fail:      func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadRequest) },
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:     "insufficient quota is permanent",
+			failures: 10,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"error":{"code":"insufficient_quota","message":"You exceeded your current quota"}}`)
+			},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:     "retry after longer than max delay",
+			failures: 10,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := newFlakyServer(t, tt.failures, tt.fail)
+			provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL), gpt.WithRetryPolicy(fastRetries))
+
+			_, err := provider.Extract(context.Background(), &llm.Request{})
+			if tt.wantErr && err == nil {
+				t.Errorf("Expected an error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, calls.Load())
+			}
+		})
+	}
+}
+
+func TestRetryHonoursRateLimitReset(t *testing.T) {
+	server, calls := newFlakyServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("x-ratelimit-remaining-requests", "10")
+		w.Header().Set("x-ratelimit-reset-requests", "5s")
+		w.Header().Set("x-ratelimit-remaining-tokens", "0")
+		w.Header().Set("x-ratelimit-reset-tokens", "100ms")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	provider := gpt.NewProvider(gpt.WithBaseUrl(server.URL), gpt.WithRetryPolicy(fastRetries))
+
+	start := time.Now()
+	if _, err := provider.Extract(context.Background(), &llm.Request{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected to wait for the token limit to reset, waited %v", elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls.Load())
+	}
+}
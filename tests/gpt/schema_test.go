@@ -0,0 +1,129 @@
+package gpt_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samredway/scrapeai/gpt/schema"
+)
+
+type Price struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency" enum:"USD,EUR,GBP"`
+}
+
+type Base struct {
+	ID string `json:"id"`
+}
+
+type Product struct {
+	Base
+	Name      string    `json:"name" description:"Name of the product"`
+	Price     Price     `json:"price"`
+	Discount  *float64  `json:"discount"`
+	Rating    *int      `json:"rating" enum:"1,2,3,4,5"`
+	InStock   bool      `json:"in_stock,omitempty"`
+	Tags      []string  `json:"tags"`
+	Variants  []*Price  `json:"variants"`
+	Updated   time.Time `json:"updated"`
+	Internal  string    `json:"-"`
+	unexposed string
+}
+
+type Category struct {
+	Name     string     `json:"name"`
+	Children []Category `json:"children"`
+}
+
+type Listing struct {
+	Products []Product `json:"products"`
+	Category *Category `json:"category"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	s, err := schema.For[Product]()
+	if err != nil {
+		t.Fatalf("Error generating schema: %v", err)
+	}
+
+	expected := `{"type":"object","properties":{` +
+		`"id":{"type":"string"},` +
+		`"name":{"type":"string","description":"Name of the product"},` +
+		`"price":{"type":"object","properties":{"amount":{"type":"number"},"currency":{"type":"string","enum":["USD","EUR","GBP"]}},"additionalProperties":false,"required":["amount","currency"]},` +
+		`"discount":{"type":["number","null"]},` +
+		`"rating":{"type":["integer","null"],"enum":[1,2,3,4,5,null]},` +
+		`"in_stock":{"type":"boolean"},` +
+		`"tags":{"type":"array","items":{"type":"string"}},` +
+		`"variants":{"type":"array","items":{"anyOf":[{"type":"object","properties":{"amount":{"type":"number"},"currency":{"type":"string","enum":["USD","EUR","GBP"]}},"additionalProperties":false,"required":["amount","currency"]},{"type":"null"}]}},` +
+		`"updated":{"type":"string","description":"RFC 3339 date-time"}` +
+		`},"additionalProperties":false,` +
+		`"required":["id","name","price","discount","rating","in_stock","tags","variants","updated"]}`
+
+	if s.String() != expected {
+		t.Errorf("Unexpected schema\nexpected: %s\n     got: %s", expected, s.String())
+	}
+}
+
+func TestSchemaForRecursive(t *testing.T) {
+	s, err := schema.For[Listing]()
+	if err != nil {
+		t.Fatalf("Error generating schema: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(s.String()), &decoded); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	defs, ok := decoded["$defs"].(map[string]any)
+	if !ok || defs["Category"] == nil {
+		t.Fatalf("Expected Category to be defined in $defs, got %s", s.String())
+	}
+	if !strings.Contains(s.String(), `"category":{"anyOf":[{"$ref":"#/$defs/Category"},{"type":"null"}]}`) {
+		t.Errorf("Expected category to reference the definition, got %s", s.String())
+	}
+	if !strings.Contains(s.String(), `"children":{"type":"array","items":{"$ref":"#/$defs/Category"}}`) {
+		t.Errorf("Expected children to reference the definition, got %s", s.String())
+	}
+}
+
+func TestSchemaForErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		gen     func() (*schema.Schema, error)
+		inError string
+	}{
+		{
+			name:    "root must be struct",
+			gen:     schema.For[[]string],
+			inError: "the root of a schema must be a struct",
+		},
+		{
+			name: "maps unsupported",
+			gen: schema.For[struct {
+				Attrs map[string]string `json:"attrs"`
+			}],
+			inError: "/properties/attrs: type map[string]string cannot be expressed",
+		},
+		{
+			name: "invalid enum",
+			gen: schema.For[struct {
+				Count int `json:"count" enum:"one,two"`
+			}],
+			inError: `/properties/count: invalid enum value "one"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.gen()
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected error containing '%s' but got '%s'", tt.inError, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package gpt_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/gpt/schema"
+)
+
+func TestValidateSchemaValid(t *testing.T) {
+	schemas := map[string]string{
+		"default": gpt.DefaultSchemaTemplate,
+		"all types": `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "description": "Name", "pattern": "^[A-Z]", "format": "email"},
+				"price": {"type": "number", "minimum": 0, "exclusiveMaximum": 1000},
+				"count": {"type": "integer", "multipleOf": 1},
+				"active": {"type": "boolean"},
+				"nothing": {"type": "null"},
+				"discount": {"type": ["number", "null"]},
+				"currency": {"type": "string", "enum": ["USD", "EUR"]},
+				"rating": {"type": ["integer", "null"], "enum": [1, 2, 3, null]},
+				"sku": {"anyOf": [{"type": "string"}, {"type": "null"}]},
+				"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 5}
+			},
+			"additionalProperties": false,
+			"required": ["name", "price", "count", "active", "nothing", "discount", "currency", "rating", "sku", "tags"]
+		}`,
+		"definitions and recursion": `{
+			"type": "object",
+			"properties": {
+				"root": {"$ref": "#/$defs/node"},
+				"linked": {"anyOf": [{"$ref": "#"}, {"type": "null"}]}
+			},
+			"additionalProperties": false,
+			"required": ["root", "linked"],
+			"$defs": {
+				"node": {
+					"type": "object",
+					"properties": {
+						"value": {"type": "string"},
+						"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+					},
+					"additionalProperties": false,
+					"required": ["value", "children"]
+				}
+			}
+		}`,
+	}
+
+	for name, s := range schemas {
+		t.Run(name, func(t *testing.T) {
+			if err := gpt.ValidateSchema(s); err != nil {
+				t.Errorf("Expected schema to be valid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaGenerated(t *testing.T) {
+	s, err := schema.For[Listing]()
+	if err != nil {
+		t.Fatalf("Error generating schema: %v", err)
+	}
+	if err := gpt.ValidateSchema(s.String()); err != nil {
+		t.Errorf("Expected generated schema to be valid, got %v", err)
+	}
+}
+
+func TestValidateSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		limits  *gpt.SchemaLimits
+		inError string
+	}{
+		{
+			name:    "root must be an object",
+			schema:  `{"type": "array", "items": {"type": "string"}}`,
+			inError: "/: the root schema must have type 'object'",
+		},
+		{
+			name:    "root must not be anyOf",
+			schema:  `{"anyOf": [{"type": "object", "properties": {}, "additionalProperties": false}]}`,
+			inError: "/: the root schema must not use anyOf",
+		},
+		{
+			name: "later properties are checked",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"a": {"type": "string"},
+					"b": {"type": "object", "properties": {"c": {"type": "string"}}, "required": ["c"]}
+				},
+				"additionalProperties": false,
+				"required": ["a", "b"]
+			}`,
+			inError: "/properties/b: an object must contain the additionalProperties field",
+		},
+		{
+			name: "invalid type",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"type": "date"}},
+				"additionalProperties": false,
+				"required": ["a"]
+			}`,
+			inError: "/properties/a/type: invalid value 'date' for key 'type'",
+		},
+		{
+			name: "unsupported keyword",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"type": "string", "minLength": 2}},
+				"additionalProperties": false,
+				"required": ["a"]
+			}`,
+			inError: "/properties/a: keyword 'minLength' is not supported",
+		},
+		{
+			name: "enum value does not match type",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"type": "integer", "enum": [1, "two"]}},
+				"additionalProperties": false,
+				"required": ["a"]
+			}`,
+			inError: "/properties/a/enum/1: enum value two does not match type integer",
+		},
+		{
+			name: "unknown reference",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"items": {"$ref": "#/$defs/missing"}, "type": "array"}},
+				"additionalProperties": false,
+				"required": ["a"]
+			}`,
+			inError: "/properties/a/items/$ref: $ref #/$defs/missing does not match any definition",
+		},
+		{
+			name: "invalid definition",
+			schema: `{
+				"type": "object",
+				"properties": {},
+				"additionalProperties": false,
+				"$defs": {"thing": {"type": "object", "properties": {}}}
+			}`,
+			inError: "/$defs/thing: an object must contain the additionalProperties field",
+		},
+		{
+			name: "required property not defined",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"type": "string"}},
+				"additionalProperties": false,
+				"required": ["a", "b"]
+			}`,
+			inError: "/required: required property b is not defined in properties",
+		},
+		{
+			name: "depth limit",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"type": "array", "items": {"type": "array", "items": {"type": "string"}}}},
+				"additionalProperties": false,
+				"required": ["a"]
+			}`,
+			limits:  &gpt.SchemaLimits{MaxDepth: 3, MaxProperties: 10, MaxEnumValues: 10, MaxStringLength: 100},
+			inError: "/properties/a/items/items: schema exceeds the maximum nesting depth of 3",
+		},
+		{
+			name: "enum limit",
+			schema: `{
+				"type": "object",
+				"properties": {"a": {"type": "string", "enum": ["x", "y", "z"]}},
+				"additionalProperties": false,
+				"required": ["a"]
+			}`,
+			limits:  &gpt.SchemaLimits{MaxDepth: 10, MaxProperties: 10, MaxEnumValues: 2, MaxStringLength: 100},
+			inError: "/properties/a/enum: schema exceeds the maximum of 2 enum values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.limits != nil {
+				err = gpt.ValidateSchemaWithLimits(tt.schema, *tt.limits)
+			} else {
+				err = gpt.ValidateSchema(tt.schema)
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing '%s'", tt.inError)
+			}
+			if !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected error containing '%s' but got '%s'", tt.inError, err.Error())
+			}
+			var schemaErr *gpt.SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Errorf("Expected a SchemaError, got %T", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package llm_test
+
+import (
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+)
+
+func TestLookupModel(t *testing.T) {
+	info, ok := llm.LookupModel("gpt-4o-mini-2024-07-18")
+	if !ok || info.Name != "gpt-4o-mini" {
+		t.Errorf("Expected dated snapshot to resolve to gpt-4o-mini, got %+v", info)
+	}
+	if _, ok := llm.LookupModel("not-a-model"); ok {
+		t.Errorf("Expected unknown model not to be found")
+	}
+}
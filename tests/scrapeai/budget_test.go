@@ -0,0 +1,199 @@
+package scrapeai_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestSharedBudget(t *testing.T) {
+	server := newTestServer(t, testPage)
+	budget := &scrapeai.Budget{MaxTokens: 2000}
+	provider := reportUsage("gpt-4o-mini")
+
+	scrape := func() error {
+		req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+			scrapeai.WithFetchFunc(scraping.Fetch),
+			scrapeai.WithProvider(provider),
+			scrapeai.WithBudget(budget),
+		)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		_, err = scrapeai.Scrape(context.Background(), req)
+		return err
+	}
+
+	// each call reports 1100 tokens, so the third scrape is over budget
+	for i := 0; i < 2; i++ {
+		if err := scrape(); err != nil {
+			t.Fatalf("Expected scrape %d to be within budget, got %v", i+1, err)
+		}
+	}
+	if err := scrape(); !errors.Is(err, scrapeai.ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if provider.calls() != 2 {
+		t.Errorf("Expected the provider not to be called once over budget, got %d calls", provider.calls())
+	}
+	if tokens, cost := budget.Spent(); tokens != 2200 || cost <= 0 {
+		t.Errorf("Expected spend of 2200 tokens at a non zero cost, got %d tokens and $%f", tokens, cost)
+	}
+}
+
+func TestPerRequestBudget(t *testing.T) {
+	server := newTestServer(t, productPage(20))
+
+	tests := []struct {
+		name    string
+		budget  *scrapeai.Budget
+		wantErr bool
+	}{
+		{
+			name:    "refuse",
+			budget:  &scrapeai.Budget{PerRequestTokens: 1500},
+			wantErr: true,
+		},
+		{
+			name:   "truncate",
+			budget: &scrapeai.Budget{PerRequestTokens: 1500, OnExceeded: scrapeai.BudgetTruncate},
+		},
+		{
+			// each call costs $0.00021 at gpt-4o-mini prices
+			name:   "truncate on cost",
+			budget: &scrapeai.Budget{PerRequestCost: 0.0003, OnExceeded: scrapeai.BudgetTruncate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := reportUsage("gpt-4o-mini")
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the paragraphs",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithMaxChunkTokens(40),
+				scrapeai.WithBudget(tt.budget),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			result, err := scrapeai.Scrape(context.Background(), req)
+			if tt.wantErr {
+				if !errors.Is(err, scrapeai.ErrBudgetExceeded) {
+					t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected partial results, got %v", err)
+			}
+			if provider.calls() != 2 || len(result.Usage.Calls) != 2 {
+				t.Errorf("Expected scraping to stop after 2 calls, got %d", provider.calls())
+			}
+		})
+	}
+}
+
+func TestBudgetTruncateWithoutResults(t *testing.T) {
+	server := newTestServer(t, productPage(100))
+	// the first response is cut off and uses up the budget, so the pieces it
+	// is split into are never extracted
+	provider := &stubProvider{model: "gpt-4o-mini", respond: func(req *llm.Request) llm.Response {
+		return llm.Response{
+			Content:      `{"data":["Prod`,
+			Model:        "gpt-4o-mini",
+			FinishReason: "length",
+			Usage:        llm.Usage{PromptTokens: 1000, CompletionTokens: 30000, TotalTokens: 31000},
+		}
+	}}
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+		scrapeai.WithBudget(&scrapeai.Budget{PerRequestTokens: 30000, OnExceeded: scrapeai.BudgetTruncate}),
+		scrapeai.WithTruncationRetries(0),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	result, err := scrapeai.Scrape(context.Background(), req)
+	if !errors.Is(err, scrapeai.ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded when nothing was extracted, got %v with %+v", err, result)
+	}
+	if provider.calls() != 1 {
+		t.Errorf("Expected a single call, got %d", provider.calls())
+	}
+}
+
+func TestBudgetLimitsRetries(t *testing.T) {
+	server := newTestServer(t, testPage)
+
+	// reports the tokens it was sent and fills whatever output limit it is
+	// given, as a model producing a long response would
+	respond := func(finishReason string, content string) func(req *llm.Request) llm.Response {
+		return func(req *llm.Request) llm.Response {
+			input := req.Prompt + "\n\n" + req.Page
+			if req.Repair != nil {
+				input += "\n\n" + req.Repair.Response + "\n\n" + llm.RepairInstructions(req.Repair.Problem)
+			}
+			prompt := scraping.EstimateTokens(input)
+			completion := cmp.Or(req.MaxTokens, 4096)
+			return llm.Response{
+				Content:      content,
+				Model:        "gpt-4o-mini",
+				FinishReason: finishReason,
+				Usage:        llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		response func(req *llm.Request) llm.Response
+	}{
+		{
+			name:     "truncation retries",
+			response: respond("length", `{"data": "cut o`),
+		},
+		{
+			name:     "repairs",
+			response: respond("stop", `not json`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{respond: tt.response}
+			budget := &scrapeai.Budget{PerRequestTokens: 2000}
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithBudget(budget),
+				scrapeai.WithRepairAttempts(5),
+				scrapeai.WithTruncationRetries(5),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			_, err = scrapeai.Scrape(context.Background(), req)
+			if !errors.Is(err, scrapeai.ErrBudgetExceeded) {
+				t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+			}
+			if tokens, _ := budget.Spent(); tokens > 2000 {
+				t.Errorf("Expected at most 2000 tokens to be spent, got %d over %d calls", tokens, len(provider.requests))
+			}
+			for _, call := range provider.requests {
+				if call.MaxTokens <= 0 || call.MaxTokens > 2000 {
+					t.Errorf("Expected each call's output to be capped by the budget, got %d", call.MaxTokens)
+				}
+			}
+		})
+	}
+}
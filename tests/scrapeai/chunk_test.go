@@ -0,0 +1,80 @@
+package scrapeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+const listingSchema = `{
+	"type": "object",
+	"properties": {
+		"title": {"type": "string"},
+		"products": {"type": "array", "items": {"type": "string"}}
+	},
+	"additionalProperties": false,
+	"required": ["title", "products"]
+}`
+
+func TestScrapeChunksLargePages(t *testing.T) {
+	page := strings.Replace(productPage(40), "<ul>", "<h1>Catalogue</h1><ul>", 1)
+	// a duplicate product which should be removed when merging
+	page = strings.Replace(page, "</ul>", "<li>Product 0</li></ul>", 1)
+	server := newTestServer(t, page)
+
+	provider := replyFunc(func(req *llm.Request) string {
+		title := ""
+		if strings.Contains(req.Page, "Catalogue") {
+			title = "Catalogue"
+		}
+		result, _ := json.Marshal(map[string]any{
+			"title":    title,
+			"products": productRe.FindAllString(req.Page, -1),
+		})
+		return string(result)
+	})
+
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+		scrapeai.WithSchema(listingSchema),
+		scrapeai.WithMaxChunkTokens(60),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	result, err := scrapeai.Scrape(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	if provider.calls() < 2 {
+		t.Fatalf("Expected the page to be chunked into several requests, got %d", provider.calls())
+	}
+
+	var merged struct {
+		Title    string   `json:"title"`
+		Products []string `json:"products"`
+	}
+	if err := json.Unmarshal([]byte(result.Results), &merged); err != nil {
+		t.Fatalf("Error unmarshalling merged results: %v", err)
+	}
+	if merged.Title != "Catalogue" {
+		t.Errorf("Expected title to resolve to the non empty value, got %q", merged.Title)
+	}
+	if len(merged.Products) != 40 {
+		t.Errorf("Expected 40 unique products, got %d: %v", len(merged.Products), merged.Products)
+	}
+	for i, product := range merged.Products {
+		if product != fmt.Sprintf("Product %d", i) {
+			t.Errorf("Expected products in page order, got %s at %d", product, i)
+			break
+		}
+	}
+}
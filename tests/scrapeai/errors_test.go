@@ -0,0 +1,131 @@
+package scrapeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeErrorTypes(t *testing.T) {
+	page := newTestServer(t, testPage)
+	missing := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(missing.Close)
+	quota := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`)
+	}))
+	t.Cleanup(quota.Close)
+
+	tests := []struct {
+		name     string
+		url      string
+		options  []scrapeai.Option
+		sentinel error
+		check    func(t *testing.T, err error)
+	}{
+		{
+			name: "fetch status",
+			url:  missing.URL,
+			check: func(t *testing.T, err error) {
+				var fetchErr *scrapeai.FetchError
+				if !errors.As(err, &fetchErr) || fetchErr.StatusCode != http.StatusNotFound {
+					t.Errorf("Expected a FetchError with status 404, got %v", err)
+				}
+			},
+		},
+		{
+			name: "custom fetch func",
+			url:  page.URL,
+			options: []scrapeai.Option{scrapeai.WithFetchFunc(func(ctx context.Context, url string) (string, error) {
+				return "", errors.New("blocked")
+			})},
+			check: func(t *testing.T, err error) {
+				var fetchErr *scrapeai.FetchError
+				if !errors.As(err, &fetchErr) || fetchErr.URL != page.URL || fetchErr.StatusCode != 0 {
+					t.Errorf("Expected a FetchError for %s, got %v", page.URL, err)
+				}
+			},
+		},
+		{
+			name: "api error",
+			url:  page.URL,
+			options: []scrapeai.Option{scrapeai.WithProvider(gpt.NewProvider(
+				gpt.WithBaseUrl(quota.URL),
+				gpt.WithRetryPolicy(gpt.RetryPolicy{}),
+			))},
+			check: func(t *testing.T, err error) {
+				var apiErr *scrapeai.APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("Expected an APIError, got %v", err)
+				}
+				if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Code != "insufficient_quota" ||
+					apiErr.Type != "insufficient_quota" || apiErr.Message != "You exceeded your current quota" {
+					t.Errorf("Unexpected APIError fields: %+v", apiErr)
+				}
+			},
+		},
+		{
+			name: "refusal",
+			url:  page.URL,
+			options: []scrapeai.Option{scrapeai.WithProvider(replyResponse(
+				llm.Response{Refusal: "I can't help with that", FinishReason: "stop"},
+			))},
+			sentinel: scrapeai.ErrRefusal,
+		},
+		{
+			name: "truncated",
+			url:  page.URL,
+			options: []scrapeai.Option{scrapeai.WithProvider(replyResponse(
+				llm.Response{Content: `{"data":["Exam`, FinishReason: "length"},
+			))},
+			sentinel: scrapeai.ErrTruncated,
+		},
+		{
+			name: "invalid json",
+			url:  page.URL,
+			options: []scrapeai.Option{
+				scrapeai.WithProvider(replyResponse(llm.Response{Content: `not json`})),
+				scrapeai.WithRepairAttempts(0),
+			},
+			sentinel: scrapeai.ErrInvalidJSON,
+		},
+		{
+			name: "schema mismatch",
+			url:  page.URL,
+			options: []scrapeai.Option{
+				scrapeai.WithProvider(replyResponse(llm.Response{Content: `{"data":[1]}`})),
+				scrapeai.WithRepairAttempts(0),
+			},
+			sentinel: scrapeai.ErrSchemaMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]scrapeai.Option{scrapeai.WithFetchFunc(scraping.Fetch)}, tt.options...)
+			req, err := scrapeai.NewScrapeAiRequest(tt.url, "Extract the main headline", options...)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			_, err = scrapeai.Scrape(context.Background(), req)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("Expected error to match %v, got %v", tt.sentinel, err)
+			}
+			if tt.check != nil {
+				tt.check(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package scrapeai_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeReportsPageMetadata(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/shop/page", http.StatusFound)
+	})
+	mux.HandleFunc("/shop/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body><a href="item">Widget</a></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name   string
+		option scrapeai.Option
+		status int
+		url    string
+		link   string
+	}{
+		{
+			name:   "fetcher",
+			option: scrapeai.WithFetcher(scraping.FetcherFunc(scraping.FetchHTTP)),
+			status: http.StatusOK,
+			url:    server.URL + "/shop/page",
+			link:   server.URL + "/shop/item",
+		},
+		{
+			name:   "fetch func",
+			option: scrapeai.WithFetchFunc(scraping.Fetch),
+			url:    server.URL + "/old",
+			link:   server.URL + "/item",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := replyWith(`{"data":["Widget"]}`)
+			req, err := scrapeai.NewScrapeAiRequest(server.URL+"/old", "Extract the links",
+				tt.option,
+				scrapeai.WithProvider(provider),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			result, err := scrapeai.Scrape(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if result.Page == nil || result.Page.StatusCode != tt.status || result.Page.URL != tt.url {
+				t.Fatalf("Unexpected page metadata: %+v", result.Page)
+			}
+			if !strings.Contains(result.Page.Text(), "Widget") {
+				t.Errorf("Expected the page body to be reported, got %q", result.Page.Text())
+			}
+			// relative links resolve against the final URL when it is known
+			if !strings.Contains(provider.last().Page, `href="`+tt.link+`"`) {
+				t.Errorf("Expected the link to resolve to %s, got %s", tt.link, provider.last().Page)
+			}
+		})
+	}
+}
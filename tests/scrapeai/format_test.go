@@ -0,0 +1,83 @@
+package scrapeai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeContentFormats(t *testing.T) {
+	server := newTestServer(t, testPage)
+
+	tests := []struct {
+		name   string
+		format scrapeai.ContentFormat
+		page   string
+	}{
+		{name: "html", format: scrapeai.FormatHTML, page: "<h1>Example Domain</h1>"},
+		{name: "markdown", format: scrapeai.FormatMarkdown, page: "# Example Domain\n\nSome body text"},
+		{name: "text", format: scrapeai.FormatText, page: "Example Domain\n\nSome body text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := replyWith(`{"data":["Example Domain"]}`)
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithContentFormat(tt.format),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			if _, err := scrapeai.Scrape(context.Background(), req); err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if tt.format == scrapeai.FormatHTML {
+				if !strings.Contains(provider.last().Page, tt.page) {
+					t.Errorf("Expected the page to be sent as HTML, got %q", provider.last().Page)
+				}
+				return
+			}
+			if provider.last().Page != tt.page {
+				t.Errorf("Expected page %q, got %q", tt.page, provider.last().Page)
+			}
+		})
+	}
+}
+
+func TestScrapeChunksConvertedContent(t *testing.T) {
+	server := newTestServer(t, productPage(100))
+
+	var pages []string
+	provider := &stubProvider{respond: func(req *llm.Request) llm.Response {
+		pages = append(pages, req.Page)
+		return llm.Response{Content: `{"data":[]}`, FinishReason: "stop"}
+	}}
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+		scrapeai.WithContentFormat(scrapeai.FormatMarkdown),
+		scrapeai.WithMaxChunkTokens(200),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	if _, err := scrapeai.Scrape(context.Background(), req); err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	if len(pages) < 2 {
+		t.Fatalf("Expected the page to be chunked, got %d requests", len(pages))
+	}
+	for _, page := range pages {
+		if strings.Contains(page, "<li>") || !strings.HasPrefix(page, "- Product") {
+			t.Errorf("Expected each chunk to be sent as Markdown, got %q", page)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package scrapeai_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/samredway/scrapeai/llm"
+)
+
+// productPage returns a page listing n products
+func productPage(n int) string {
+	var sb strings.Builder
+	sb.WriteString("<html><body><ul>")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "<li>Product %d</li>", i)
+	}
+	sb.WriteString("</ul></body></html>")
+	return sb.String()
+}
+
+var productRe = regexp.MustCompile(`Product \d+`)
+
+// stubProvider records every request and replies with the response built by
+// respond, or fails with err when it is set. Model reports model, which is
+// empty for providers that do not know their model up front
+type stubProvider struct {
+	mu       sync.Mutex
+	model    string
+	err      error
+	respond  func(req *llm.Request) llm.Response
+	requests []llm.Request
+}
+
+func (p *stubProvider) Extract(ctx context.Context, req *llm.Request) (*llm.Response, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, *req)
+	p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	response := p.respond(req)
+	return &response, nil
+}
+
+func (p *stubProvider) Model() string {
+	return p.model
+}
+
+// calls returns the number of requests made to the provider
+func (p *stubProvider) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.requests)
+}
+
+// last returns the most recent request made to the provider
+func (p *stubProvider) last() llm.Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.requests) == 0 {
+		return llm.Request{}
+	}
+	return p.requests[len(p.requests)-1]
+}
+
+// replyWith returns a provider replying to every request with the content
+func replyWith(content string) *stubProvider {
+	return replyFunc(func(req *llm.Request) string { return content })
+}
+
+// replyFunc returns a provider replying with the content built by fn
+func replyFunc(fn func(req *llm.Request) string) *stubProvider {
+	return &stubProvider{respond: func(req *llm.Request) llm.Response {
+		return llm.Response{Content: fn(req)}
+	}}
+}
+
+// replyResponse returns a provider replying to every request with the response
+func replyResponse(response llm.Response) *stubProvider {
+	return &stubProvider{respond: func(req *llm.Request) llm.Response { return response }}
+}
+
+// failWith returns a provider failing every request with err
+func failWith(err error) *stubProvider {
+	return &stubProvider{err: err}
+}
+
+// reportUsage returns a provider reporting fixed usage by the model for every
+// call, numbering its responses from resp-1
+func reportUsage(model string) *stubProvider {
+	p := &stubProvider{}
+	p.respond = func(req *llm.Request) llm.Response {
+		return llm.Response{
+			Content:      `{"data":[]}`,
+			Usage:        llm.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+			Model:        model,
+			ID:           fmt.Sprintf("resp-%d", p.calls()),
+			FinishReason: "stop",
+		}
+	}
+	return p
+}
@@ -0,0 +1,50 @@
+package scrapeai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+type article struct {
+	Headline string   `json:"headline" description:"The main headline"`
+	Body     string   `json:"body"`
+	Authors  []string `json:"authors"`
+}
+
+func TestScrapeInto(t *testing.T) {
+	server := newTestServer(t, testPage)
+	content := `{"headline":"Example Domain","body":"Some body text","authors":["Sam"]}`
+	provider := replyWith(content)
+
+	got, result, err := scrapeai.ScrapeInto[article](context.Background(), server.URL,
+		"Extract the headline and body",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+	)
+	if err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+
+	if got.Headline != "Example Domain" || got.Body != "Some body text" || len(got.Authors) != 1 {
+		t.Errorf("Unexpected decoded result: %+v", got)
+	}
+	if result.Results != content {
+		t.Errorf("Expected raw results to be returned, got %s", result.Results)
+	}
+	for _, part := range []string{`"headline"`, `"The main headline"`, `"required":["headline","body","authors"]`} {
+		if !strings.Contains(provider.last().Schema, part) {
+			t.Errorf("Expected generated schema to contain %s, got %s", part, provider.last().Schema)
+		}
+	}
+}
+
+func TestScrapeIntoInvalidType(t *testing.T) {
+	_, _, err := scrapeai.ScrapeInto[[]string](context.Background(), "https://example.com", "Extract")
+	if err == nil || !strings.Contains(err.Error(), "generating schema") {
+		t.Errorf("Expected schema generation error, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package scrapeai_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeMainContent(t *testing.T) {
+	server := newTestServer(t, `<html><body>
+<nav><a href="/">Home</a> <a href="/about">About us and our long history</a></nav>
+<article><h1>Widgets</h1><p>Widgets are made in a factory, by skilled workers, over many years.</p>
+<p>Each one is pressed, cut, polished and packed before being shipped.</p></article>
+<footer><p>Copyright 2024 Widget Co. All rights reserved, terms apply.</p></footer>
+</body></html>`)
+
+	provider := replyWith(`{"data":["Widgets"]}`)
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the headline",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+		scrapeai.WithMainContent(true),
+		scrapeai.WithContentFormat(scrapeai.FormatText),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	if _, err := scrapeai.Scrape(context.Background(), req); err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	expected := "Widgets\n\nWidgets are made in a factory, by skilled workers, over many years.\n\n" +
+		"Each one is pressed, cut, polished and packed before being shipped."
+	if provider.last().Page != expected {
+		t.Errorf("Expected only the article to be sent, got %q", provider.last().Page)
+	}
+}
@@ -0,0 +1,101 @@
+package scrapeai_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+const testPage = `<html><head><script>var x = 1;</script></head>
+<body><h1>Example Domain</h1><p>Some body text</p></body></html>`
+
+func newTestServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrapeWithProvider(t *testing.T) {
+	server := newTestServer(t, testPage)
+	content := `{"data":["Example Domain"]}`
+	provider := replyWith(content)
+
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	result, err := scrapeai.Scrape(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+
+	if result.Results != content {
+		t.Errorf("Expected results %q, got %q", content, result.Results)
+	}
+	if provider.last().Prompt != "Extract the main headline" {
+		t.Errorf("Unexpected prompt passed to provider: %q", provider.last().Prompt)
+	}
+	if !strings.Contains(provider.last().Page, "Example Domain") {
+		t.Errorf("Expected page passed to provider to contain headline, got %q", provider.last().Page)
+	}
+	if strings.Contains(provider.last().Page, "<script>") {
+		t.Errorf("Expected script tags to be stripped, got %q", provider.last().Page)
+	}
+	if provider.last().Schema == "" {
+		t.Errorf("Expected the default schema to be passed to the provider")
+	}
+}
+
+func TestScrapeWithProviderErrors(t *testing.T) {
+	server := newTestServer(t, testPage)
+
+	tests := []struct {
+		name     string
+		provider *stubProvider
+		inError  string
+	}{
+		{
+			name:     "provider error",
+			provider: failWith(fmt.Errorf("backend unavailable")),
+			inError:  "backend unavailable",
+		},
+		{
+			name:     "invalid json",
+			provider: replyWith(`{"data": [`),
+			inError:  "invalid JSON response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(tt.provider),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+			_, err = scrapeai.Scrape(context.Background(), req)
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected error containing '%s' but got '%s'", tt.inError, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package scrapeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samredway/scrapeai/gpt"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+// newSequenceServer is an OpenAI compatible server which replies with each
+// of the contents in turn and records the messages of every request
+func newSequenceServer(t *testing.T, contents []string, messages *[][]gpt.GptMessage) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request gpt.GptRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Error decoding request: %v", err)
+		}
+		*messages = append(*messages, request.Messages)
+
+		content := contents[min(int(calls.Add(1))-1, len(contents)-1)]
+		encoded, _ := json.Marshal(content)
+		fmt.Fprintf(w, `{"id":"chatcmpl-1","model":"llama3.1","choices":[{"index":0,`+
+			`"message":{"role":"assistant","content":%s},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`, encoded)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrapeRepairsInvalidResponses(t *testing.T) {
+	page := newTestServer(t, testPage)
+
+	tests := []struct {
+		name      string
+		contents  []string
+		attempts  int
+		wantCalls int
+		inError   string
+	}{
+		{
+			name:      "schema mismatch repaired",
+			contents:  []string{`{"data":"Example Domain"}`, `{"data":["Example Domain"]}`},
+			attempts:  1,
+			wantCalls: 2,
+		},
+		{
+			name:      "invalid json repaired",
+			contents:  []string{`{"data":["Example`, `{"data":["Example Domain"]}`},
+			attempts:  1,
+			wantCalls: 2,
+		},
+		{
+			name:      "repairs disabled",
+			contents:  []string{`{"data":"Example Domain"}`, `{"data":["Example Domain"]}`},
+			attempts:  0,
+			wantCalls: 1,
+			inError:   "response does not match schema: /data: expected array but got string",
+		},
+		{
+			name:      "repairs exhausted",
+			contents:  []string{`{"data":"Example Domain"}`},
+			attempts:  2,
+			wantCalls: 3,
+			inError:   "response does not match schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages [][]gpt.GptMessage
+			api := newSequenceServer(t, tt.contents, &messages)
+			provider := gpt.NewProvider(
+				gpt.WithBaseUrl(api.URL),
+				gpt.WithModel("llama3.1"),
+				gpt.WithSchemaMode(gpt.SchemaModeFormat),
+			)
+			req, err := scrapeai.NewScrapeAiRequest(page.URL, "Extract the main headline",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithRepairAttempts(tt.attempts),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			result, err := scrapeai.Scrape(context.Background(), req)
+			if len(messages) != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, len(messages))
+			}
+			if tt.inError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected error containing '%s', got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if result.Results != `{"data":["Example Domain"]}` {
+				t.Errorf("Unexpected results: %s", result.Results)
+			}
+			if len(result.Usage.Calls) != tt.wantCalls {
+				t.Errorf("Expected usage for every call including repairs, got %d", len(result.Usage.Calls))
+			}
+
+			repair := messages[1]
+			if len(repair) != 3 || repair[1].Role != "assistant" || repair[1].Content != tt.contents[0] {
+				t.Fatalf("Expected the rejected response to be sent back, got %+v", repair)
+			}
+			if !strings.Contains(repair[2].Content, "was rejected") {
+				t.Errorf("Expected repair instructions, got %q", repair[2].Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package scrapeai_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeSelectors(t *testing.T) {
+	server := newTestServer(t, `<html><body>
+<nav><a href="/">Home</a></nav>
+<div id="product-grid"><div class="item">Widget</div><div class="item sponsored">Gadget</div></div>
+<div class="reviews">Great shop</div>
+</body></html>`)
+
+	tests := []struct {
+		name    string
+		options []scrapeai.Option
+		page    string
+		inError string
+	}{
+		{
+			name:    "selector",
+			options: []scrapeai.Option{scrapeai.WithSelector("#product-grid")},
+			page:    "Widget\n\nGadget",
+		},
+		{
+			name: "selector and exclusions",
+			options: []scrapeai.Option{
+				scrapeai.WithSelector("//div[@id='product-grid']"),
+				scrapeai.WithExcludeSelectors(".sponsored"),
+			},
+			page: "Widget",
+		},
+		{
+			name:    "exclusions only",
+			options: []scrapeai.Option{scrapeai.WithExcludeSelectors("nav", ".reviews")},
+			page:    "Widget\n\nGadget",
+		},
+		{
+			name:    "no match",
+			options: []scrapeai.Option{scrapeai.WithSelector("#missing")},
+			inError: "selecting content: selectors matched no elements",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := replyWith(`{"data":["Widget"]}`)
+			options := append([]scrapeai.Option{
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithContentFormat(scrapeai.FormatText),
+			}, tt.options...)
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products", options...)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			_, err = scrapeai.Scrape(context.Background(), req)
+			if tt.inError != "" {
+				if !errors.Is(err, scrapeai.ErrNoMatch) || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected error containing '%s', got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if provider.last().Page != tt.page {
+				t.Errorf("Expected page %q, got %q", tt.page, provider.last().Page)
+			}
+		})
+	}
+}
+
+func TestInvalidSelectors(t *testing.T) {
+	for _, option := range []scrapeai.Option{
+		scrapeai.WithSelector("div[["),
+		scrapeai.WithExcludeSelectors("//div[@"),
+	} {
+		_, err := scrapeai.NewScrapeAiRequest("https://example.com", "Extract", option)
+		if err == nil || !strings.Contains(err.Error(), "invalid selector") {
+			t.Errorf("Expected an invalid selector error, got %v", err)
+		}
+	}
+}
+
+func TestScrapeSelectorsKeepBase(t *testing.T) {
+	server := newTestServer(t, `<html><head><base href="https://cdn.example.com/shop/"></head><body>
+<nav><a href="/">Home</a></nav>
+<div id="grid"><a href="item/1">Widget</a></div>
+</body></html>`)
+	provider := replyWith(`{"data":["Widget"]}`)
+
+	req, err := scrapeai.NewScrapeAiRequest(server.URL+"/a/", "Extract the products",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+		scrapeai.WithSelector("#grid"),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	if _, err := scrapeai.Scrape(context.Background(), req); err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	if page := provider.last().Page; !strings.Contains(page, `href="https://cdn.example.com/shop/item/1"`) {
+		t.Errorf("Expected links to resolve against the page's base, got %q", page)
+	}
+}
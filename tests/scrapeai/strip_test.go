@@ -0,0 +1,64 @@
+package scrapeai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeStripPolicy(t *testing.T) {
+	server := newTestServer(t, `<html><body><div class="card">`+
+		`<a href="/products/widget" class="link"><img src="/img/widget.png" alt="Widget"></a>`+
+		`<span class="price" data-currency="USD">$10</span></div></body></html>`)
+
+	tests := []struct {
+		name    string
+		options []scrapeai.Option
+		page    string
+	}{
+		{
+			name:    "default",
+			options: []scrapeai.Option{scrapeai.WithContentFormat(scrapeai.FormatMarkdown)},
+			page:    "[![Widget](" + server.URL + "/img/widget.png)](" + server.URL + "/products/widget)$10",
+		},
+		{
+			name: "custom",
+			options: []scrapeai.Option{scrapeai.WithStripPolicy(scraping.StripPolicy{
+				Attributes: []string{"data-currency"},
+			})},
+			page: `<span data-currency="USD">$10</span>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := replyWith(`{"data":["Widget"]}`)
+			options := append([]scrapeai.Option{
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+			}, tt.options...)
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products", options...)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			if _, err := scrapeai.Scrape(context.Background(), req); err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if !strings.Contains(provider.last().Page, tt.page) {
+				t.Errorf("Expected page to contain %q, got %q", tt.page, provider.last().Page)
+			}
+		})
+	}
+}
+
+func TestStripPolicyInvalidSelector(t *testing.T) {
+	_, err := scrapeai.NewScrapeAiRequest("https://example.com", "Extract",
+		scrapeai.WithStripPolicy(scraping.StripPolicy{RemoveElements: []string{"div[["}}))
+	if err == nil || !strings.Contains(err.Error(), "invalid selector") {
+		t.Errorf("Expected an invalid selector error, got %v", err)
+	}
+}
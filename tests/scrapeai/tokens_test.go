@@ -0,0 +1,65 @@
+package scrapeai_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+	"github.com/samredway/scrapeai/tokenizer"
+)
+
+func TestScrapeOverflowPolicies(t *testing.T) {
+	llm.RegisterModel(llm.ModelInfo{
+		Name:            "tiny-model",
+		ContextWindow:   800,
+		MaxOutputTokens: 100,
+		Encoding:        tokenizer.Cl100kBase,
+	})
+
+	server := newTestServer(t, productPage(200))
+
+	tests := []struct {
+		name    string
+		policy  scrapeai.OverflowPolicy
+		calls   func(int) bool
+		wantErr bool
+	}{
+		{name: "chunk", policy: scrapeai.OverflowChunk, calls: func(n int) bool { return n > 1 }},
+		{name: "truncate", policy: scrapeai.OverflowTruncate, calls: func(n int) bool { return n == 1 }},
+		{name: "fail", policy: scrapeai.OverflowFail, calls: func(n int) bool { return n == 0 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := replyWith(`{"data":[]}`)
+			provider.model = "tiny-model"
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the paragraphs",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithOverflowPolicy(tt.policy),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			_, err = scrapeai.Scrape(context.Background(), req)
+			if tt.wantErr {
+				var limitErr *scrapeai.TokenLimitError
+				if !errors.As(err, &limitErr) {
+					t.Fatalf("Expected a TokenLimitError, got %v", err)
+				}
+				if limitErr.Model != "tiny-model" || limitErr.Tokens <= limitErr.Limit {
+					t.Errorf("Unexpected TokenLimitError: %+v", limitErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if !tt.calls(provider.calls()) {
+				t.Errorf("Unexpected number of provider calls: %d", provider.calls())
+			}
+		})
+	}
+}
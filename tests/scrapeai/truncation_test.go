@@ -0,0 +1,177 @@
+package scrapeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeRetriesTruncatedResponses(t *testing.T) {
+	server := newTestServer(t, testPage)
+	provider := &stubProvider{respond: func(req *llm.Request) llm.Response {
+		if req.MaxTokens < 200 {
+			return llm.Response{
+				Content:      `{"data":["Exam`,
+				FinishReason: "length",
+				Usage:        llm.Usage{CompletionTokens: 100},
+			}
+		}
+		return llm.Response{Content: `{"data":["Example Domain"]}`, FinishReason: "stop"}
+	}}
+
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	result, err := scrapeai.Scrape(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	if result.Results != `{"data":["Example Domain"]}` {
+		t.Errorf("Unexpected results: %s", result.Results)
+	}
+	if len(provider.requests) != 2 || provider.requests[0].MaxTokens != 0 || provider.requests[1].MaxTokens != 200 {
+		t.Errorf("Expected a retry with double the output tokens, got %+v", provider.requests)
+	}
+	if len(result.Usage.Calls) != 2 || result.Usage.Calls[0].FinishReason != "length" {
+		t.Errorf("Expected the truncated call to be included in usage, got %+v", result.Usage.Calls)
+	}
+}
+
+func TestScrapeSplitsTruncatedChunks(t *testing.T) {
+	server := newTestServer(t, productPage(200))
+
+	// responses for more than 60 products do not fit in the output limit
+	provider := &stubProvider{respond: func(req *llm.Request) llm.Response {
+		products := productRe.FindAllString(req.Page, -1)
+		if len(products) > 60 {
+			return llm.Response{Content: `{"data":["Product 0"`, FinishReason: "length"}
+		}
+		content, _ := json.Marshal(map[string]any{"data": products})
+		return llm.Response{Content: string(content), FinishReason: "stop"}
+	}}
+
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+		scrapeai.WithTruncationRetries(0),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	result, err := scrapeai.Scrape(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Error scraping: %v", err)
+	}
+	var merged struct {
+		Data []string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(result.Results), &merged); err != nil {
+		t.Fatalf("Error unmarshalling results: %v", err)
+	}
+	if len(merged.Data) != 200 || merged.Data[0] != "Product 0" || merged.Data[199] != "Product 199" {
+		t.Errorf("Expected all 200 products in page order, got %v", merged.Data)
+	}
+	if len(provider.requests) < 4 {
+		t.Errorf("Expected the page to be split into several requests, got %d", len(provider.requests))
+	}
+}
+
+func TestScrapeTruncationFailure(t *testing.T) {
+	server := newTestServer(t, testPage)
+	provider := &stubProvider{respond: func(req *llm.Request) llm.Response {
+		return llm.Response{Content: `{"data":[`, FinishReason: "length", Usage: llm.Usage{CompletionTokens: 100}}
+	}}
+
+	req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the main headline",
+		scrapeai.WithFetchFunc(scraping.Fetch),
+		scrapeai.WithProvider(provider),
+	)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	_, err = scrapeai.Scrape(context.Background(), req)
+	if !errors.Is(err, scrapeai.ErrTruncated) {
+		t.Fatalf("Expected a truncation error, got %v", err)
+	}
+	// the first attempt and the default two retries
+	if len(provider.requests) != 3 || provider.requests[2].MaxTokens != 400 {
+		t.Errorf("Expected two retries with growing limits, got %+v", provider.requests)
+	}
+}
+
+func TestScrapeRefusals(t *testing.T) {
+	server := newTestServer(t, productPage(20))
+
+	tests := []struct {
+		name     string
+		refuse   func(page string) bool
+		products int
+		refusals int
+	}{
+		{
+			name:     "some chunks refused",
+			refuse:   func(page string) bool { return strings.Contains(page, "Product 0<") },
+			products: 20,
+			refusals: 1,
+		},
+		{
+			name:     "every chunk refused",
+			refuse:   func(page string) bool { return true },
+			refusals: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{respond: func(req *llm.Request) llm.Response {
+				if tt.refuse(req.Page) {
+					return llm.Response{Refusal: "I can't help with that", FinishReason: "stop"}
+				}
+				content, _ := json.Marshal(map[string]any{"data": productRe.FindAllString(req.Page, -1)})
+				return llm.Response{Content: string(content), FinishReason: "stop"}
+			}}
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract the products",
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithMaxChunkTokens(60),
+			)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			result, err := scrapeai.Scrape(context.Background(), req)
+			if tt.refusals < 0 {
+				var refusal *scrapeai.RefusalError
+				if !errors.As(err, &refusal) || !errors.Is(err, scrapeai.ErrRefusal) {
+					t.Fatalf("Expected a RefusalError, got %v", err)
+				}
+				if refusal.Message != "I can't help with that" {
+					t.Errorf("Unexpected refusal message: %q", refusal.Message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+			if len(result.Refusals) != tt.refusals {
+				t.Errorf("Expected %d refusals, got %v", tt.refusals, result.Refusals)
+			}
+			if strings.Contains(result.Results, `"Product 0"`) {
+				t.Errorf("Expected the refused chunk to be skipped, got %s", result.Results)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package scrapeai_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/samredway/scrapeai/llm"
+	"github.com/samredway/scrapeai/scrapeai"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestScrapeUsage(t *testing.T) {
+	server := newTestServer(t, productPage(20))
+
+	tests := []struct {
+		name       string
+		model      string
+		prices     llm.PriceTable
+		callCost   float64
+		wantPriced bool
+	}{
+		{
+			name:       "default prices",
+			model:      "gpt-4o-mini-2024-07-18",
+			callCost:   (1000*0.15 + 100*0.60) / 1_000_000,
+			wantPriced: true,
+		},
+		{
+			name:       "custom prices",
+			model:      "in-house",
+			prices:     llm.PriceTable{"in-house": {InputPerMillion: 1, OutputPerMillion: 10}},
+			callCost:   (1000*1.0 + 100*10.0) / 1_000_000,
+			wantPriced: true,
+		},
+		{
+			name:       "unknown model",
+			model:      "in-house",
+			callCost:   0,
+			wantPriced: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := reportUsage(tt.model)
+			options := []scrapeai.Option{
+				scrapeai.WithFetchFunc(scraping.Fetch),
+				scrapeai.WithProvider(provider),
+				scrapeai.WithMaxChunkTokens(40),
+			}
+			if tt.prices != nil {
+				options = append(options, scrapeai.WithPrices(tt.prices))
+			}
+			req, err := scrapeai.NewScrapeAiRequest(server.URL, "Extract", options...)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			result, err := scrapeai.Scrape(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Error scraping: %v", err)
+			}
+
+			usage := result.Usage
+			calls := provider.calls()
+			if calls < 2 || len(usage.Calls) != calls {
+				t.Fatalf("Expected usage for each of several calls, got %d calls and %d records", calls, len(usage.Calls))
+			}
+			if usage.PromptTokens != 1000*calls || usage.CompletionTokens != 100*calls || usage.TotalTokens != 1100*calls {
+				t.Errorf("Expected usage to be summed across calls, got %+v", usage)
+			}
+			if math.Abs(usage.EstimatedCost-tt.callCost*float64(calls)) > 1e-12 {
+				t.Errorf("Expected cost %f, got %f", tt.callCost*float64(calls), usage.EstimatedCost)
+			}
+			if usage.Priced != tt.wantPriced {
+				t.Errorf("Expected priced to be %v", tt.wantPriced)
+			}
+			if usage.Model() != tt.model {
+				t.Errorf("Expected model %s, got %s", tt.model, usage.Model())
+			}
+			last := usage.Calls[len(usage.Calls)-1]
+			if last.ResponseID != fmt.Sprintf("resp-%d", calls) || last.FinishReason != "stop" {
+				t.Errorf("Unexpected call usage: %+v", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package scraping_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+// a shop page whose content only appears after interacting with it
+const interactivePage = `<html><body>
+<div id="cookies"><button id="accept" onclick="document.getElementById('cookies').remove()">Accept</button></div>
+<input id="search" onkeydown="if (event.key === 'Enter') document.getElementById('query').textContent = 'Searched ' + this.value">
+<p id="query"></p>
+<select id="currency" onchange="document.getElementById('price').textContent = this.value + ' 10'">
+	<option value="USD">USD</option><option value="EUR">EUR</option>
+</select>
+<p id="price">USD 10</p>
+<ul id="items"><li>Item 1</li></ul>
+<button id="more" onclick="
+	const items = document.getElementById('items');
+	items.insertAdjacentHTML('beforeend', '<li>Item ' + (items.children.length + 1) + '</li>');
+	if (items.children.length >= 3) this.remove();
+">Load more</button>
+</body></html>`
+
+func TestActions(t *testing.T) {
+	chrome := findChrome(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, interactivePage)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := scraping.NewBrowserPool(
+		scraping.WithBrowsers(1),
+		scraping.WithAllocatorOptions(append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(chrome))...),
+	)
+	defer pool.Close()
+
+	tests := []struct {
+		name     string
+		actions  []scraping.Action
+		contains []string
+		excludes []string
+		inError  string
+	}{
+		{
+			name:     "click",
+			actions:  []scraping.Action{scraping.Click("#accept")},
+			excludes: []string{`id="cookies"`},
+		},
+		{
+			name:     "repeat while present",
+			actions:  []scraping.Action{scraping.Repeat(5, scraping.IfPresent("#more", scraping.Click("#more")))},
+			contains: []string{"Item 3"},
+			excludes: []string{"Item 4", `id="more"`},
+		},
+		{
+			name:     "type",
+			actions:  []scraping.Action{scraping.Type("#search", "shoes"+kb.Enter)},
+			contains: []string{"Searched shoes"},
+		},
+		{
+			name:     "select",
+			actions:  []scraping.Action{scraping.Select("#currency", "EUR")},
+			contains: []string{"EUR 10"},
+		},
+		{
+			name: "evaluate and wait",
+			actions: []scraping.Action{
+				scraping.Evaluate(`setTimeout(() => document.body.insertAdjacentHTML("beforeend", "<p id='late'>Late</p>"), 500)`),
+				scraping.Wait(scraping.WaitVisible("#late")),
+			},
+			contains: []string{"Late"},
+		},
+		{
+			name:     "xpath and scrolling",
+			actions:  []scraping.Action{scraping.ScrollToBottom(), scraping.ScrollTo("//button[@id='more']"), scraping.Click("//button[@id='accept']")},
+			excludes: []string{`id="cookies"`},
+		},
+		{
+			name:    "missing option",
+			actions: []scraping.Action{scraping.Select("#currency", "GBP")},
+			inError: `select "GBP" in "#currency"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			fetcher := pool.Fetcher(
+				scraping.WithWait(scraping.WaitVisible("#items")),
+				scraping.WithActions(tt.actions...),
+			)
+			result, err := fetcher.Fetch(ctx, server.URL)
+			if tt.inError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error fetching: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(result.Text(), want) {
+					t.Errorf("Expected the page to contain %q, got %s", want, result.Text())
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(result.Text(), unwanted) {
+					t.Errorf("Expected the page not to contain %q, got %s", unwanted, result.Text())
+				}
+			}
+		})
+	}
+}
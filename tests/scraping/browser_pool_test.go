@@ -0,0 +1,326 @@
+package scraping_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+// findChrome returns the path of a Chrome or Chromium install, skipping the
+// test if there is none
+func findChrome(t *testing.T) string {
+	t.Helper()
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	t.Skip("Chrome is not installed")
+	return ""
+}
+
+// fakeBrowsers creates browsers which render pages without Chrome, recording
+// the browsers and tabs the pool uses. Pages report the browser and tab they
+// were rendered in, /fail fails and /crash crashes the browser
+type fakeBrowsers struct {
+	mu       sync.Mutex
+	browsers []*fakeBrowser
+	tabs     int
+}
+
+func (f *fakeBrowsers) newBrowser() scraping.Browser {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b := &fakeBrowser{factory: f, id: len(f.browsers) + 1, alive: true}
+	f.browsers = append(f.browsers, b)
+	return b
+}
+
+// closed returns the number of browsers which have been shut down
+func (f *fakeBrowsers) closed() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	closed := 0
+	for _, b := range f.browsers {
+		if b.closed {
+			closed++
+		}
+	}
+	return closed
+}
+
+type fakeBrowser struct {
+	factory *fakeBrowsers
+	id      int
+	alive   bool
+	closed  bool
+}
+
+func (b *fakeBrowser) Start() error { return nil }
+
+func (b *fakeBrowser) OpenTab() (scraping.BrowserTab, error) {
+	b.factory.mu.Lock()
+	defer b.factory.mu.Unlock()
+	b.factory.tabs++
+	return &fakeTab{browser: b, id: b.factory.tabs}, nil
+}
+
+func (b *fakeBrowser) Alive() bool {
+	b.factory.mu.Lock()
+	defer b.factory.mu.Unlock()
+	return b.alive
+}
+
+func (b *fakeBrowser) Close() {
+	b.factory.mu.Lock()
+	defer b.factory.mu.Unlock()
+	b.alive = false
+	b.closed = true
+}
+
+type fakeTab struct {
+	browser *fakeBrowser
+	id      int
+}
+
+func (t *fakeTab) Render(ctx context.Context, url string, options []scraping.PageOption) (*scraping.FetchResult, bool, error) {
+	switch {
+	case strings.HasSuffix(url, "/fail"):
+		return nil, false, &scraping.FetchError{URL: url, StatusCode: http.StatusNotFound}
+	case strings.HasSuffix(url, "/crash"):
+		t.browser.Close()
+		return nil, true, &scraping.FetchError{URL: url, Err: errors.New("target crashed")}
+	}
+	body := fmt.Sprintf("browser %d tab %d", t.browser.id, t.id)
+	return &scraping.FetchResult{Body: []byte(body), URL: url, StatusCode: http.StatusOK}, false, nil
+}
+
+func (t *fakeTab) Reset() bool { return true }
+
+func (t *fakeTab) Close() {}
+
+func TestBrowserPoolLifecycle(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []scraping.BrowserOption
+		paths    []string
+		want     []string // body of each page, empty for failures
+		launched int
+		closed   int
+	}{
+		{
+			name:     "tabs are reused",
+			options:  []scraping.BrowserOption{scraping.WithMaxPagesPerBrowser(0)},
+			paths:    []string{"/a", "/b", "/c"},
+			want:     []string{"browser 1 tab 1", "browser 1 tab 1", "browser 1 tab 1"},
+			launched: 1,
+		},
+		{
+			name:     "failed pages close their tab",
+			options:  []scraping.BrowserOption{scraping.WithMaxPagesPerBrowser(0)},
+			paths:    []string{"/a", "/fail", "/b"},
+			want:     []string{"browser 1 tab 1", "", "browser 1 tab 2"},
+			launched: 1,
+		},
+		{
+			name:     "browsers are recycled after max pages",
+			options:  []scraping.BrowserOption{scraping.WithMaxPagesPerBrowser(2)},
+			paths:    []string{"/a", "/b", "/c", "/d", "/e"},
+			want:     []string{"browser 1 tab 1", "browser 1 tab 1", "browser 2 tab 2", "browser 2 tab 2", "browser 3 tab 3"},
+			launched: 3,
+			closed:   2,
+		},
+		{
+			name:     "crashed browsers are replaced",
+			options:  []scraping.BrowserOption{scraping.WithMaxPagesPerBrowser(0)},
+			paths:    []string{"/a", "/crash", "/b"},
+			want:     []string{"browser 1 tab 1", "", "browser 2 tab 2"},
+			launched: 2,
+			closed:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browsers := &fakeBrowsers{}
+			options := append([]scraping.BrowserOption{
+				scraping.WithBrowsers(1),
+				scraping.WithTabsPerBrowser(1),
+				scraping.WithBrowserFactory(browsers.newBrowser),
+			}, tt.options...)
+			pool := scraping.NewBrowserPool(options...)
+
+			for i, path := range tt.paths {
+				result, err := pool.Fetch(context.Background(), "http://example.com"+path)
+				if tt.want[i] == "" {
+					if err == nil {
+						t.Errorf("Expected an error fetching %s", path)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Error fetching %s: %v", path, err)
+				}
+				if result.Text() != tt.want[i] {
+					t.Errorf("Expected %s to be rendered in %q, got %q", path, tt.want[i], result.Text())
+				}
+			}
+			if len(browsers.browsers) != tt.launched {
+				t.Errorf("Expected %d browsers to be launched, got %d", tt.launched, len(browsers.browsers))
+			}
+			if closed := browsers.closed(); closed != tt.closed {
+				t.Errorf("Expected %d browsers to be shut down before Close, got %d", tt.closed, closed)
+			}
+
+			pool.Close()
+			if closed := browsers.closed(); closed != tt.launched {
+				t.Errorf("Expected Close to shut down every browser, %d of %d were", closed, tt.launched)
+			}
+			if _, err := pool.Fetch(context.Background(), "http://example.com"); !errors.Is(err, scraping.ErrPoolClosed) {
+				t.Errorf("Expected ErrPoolClosed after Close, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBrowserPoolLaunchFailure(t *testing.T) {
+	pool := scraping.NewBrowserPool(
+		scraping.WithBrowsers(1),
+		scraping.WithTabsPerBrowser(1),
+		scraping.WithAllocatorOptions(chromedp.ExecPath("/nonexistent/chrome")),
+	)
+	defer pool.Close()
+
+	// a browser which fails to launch is replaced, so later fetches neither
+	// reuse it nor wait for its tab
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_, errs[i] = pool.Fetch(ctx, "http://example.com")
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		var fetchErr *scraping.FetchError
+		if !errors.As(err, &fetchErr) || !strings.Contains(err.Error(), "launching browser") {
+			t.Errorf("Expected a FetchError from launching the browser, got %v", err)
+		}
+	}
+}
+
+func TestBrowserPoolClosed(t *testing.T) {
+	pool := scraping.NewBrowserPool()
+	pool.Close()
+
+	_, err := pool.Fetch(context.Background(), "http://example.com")
+	if !errors.Is(err, scraping.ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed, got %v", err)
+	}
+	if _, err := pool.FetchFunc()(context.Background(), "http://example.com"); !errors.Is(err, scraping.ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed from FetchFunc, got %v", err)
+	}
+}
+
+func TestBrowserPoolFetch(t *testing.T) {
+	chrome := findChrome(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body><p id="path">%s</p><script>document.body.dataset.rendered = "yes"</script></body></html>`, r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := scraping.NewBrowserPool(
+		scraping.WithBrowsers(1),
+		scraping.WithTabsPerBrowser(2),
+		scraping.WithMaxPagesPerBrowser(2),
+		scraping.WithAllocatorOptions(append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(chrome))...),
+	)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path := fmt.Sprintf("/page-%d", i)
+			result, err := pool.Fetch(ctx, server.URL+path)
+			if err != nil {
+				t.Errorf("Error fetching %s: %v", path, err)
+				return
+			}
+			if !strings.Contains(result.Text(), path) || !strings.Contains(result.Text(), `data-rendered="yes"`) {
+				t.Errorf("Expected the rendered page for %s, got %s", path, result.Text())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBrowserPoolReusesTabs(t *testing.T) {
+	chrome := findChrome(t)
+	// each page reports the length of its tab's history, which grows while a
+	// tab is reused and starts again in a new one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><body><script>document.body.dataset.history = history.length</script></body></html>`)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := scraping.NewBrowserPool(
+		scraping.WithBrowsers(1),
+		scraping.WithTabsPerBrowser(1),
+		scraping.WithMaxPagesPerBrowser(0),
+		scraping.WithAllocatorOptions(append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(chrome))...),
+	)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	history := func() int {
+		t.Helper()
+		result, err := pool.Fetch(ctx, server.URL)
+		if err != nil {
+			t.Fatalf("Error fetching: %v", err)
+		}
+		_, after, _ := strings.Cut(result.Text(), `data-history="`)
+		value, _, _ := strings.Cut(after, `"`)
+		length, err := strconv.Atoi(value)
+		if err != nil {
+			t.Fatalf("Expected the history length in the page, got %s", result.Text())
+		}
+		return length
+	}
+
+	first := history()
+	if second := history(); second <= first {
+		t.Errorf("Expected the tab to be reused, got history of %d after %d", second, first)
+	}
+	// a failed page closes its tab
+	if _, err := pool.Fetch(ctx, server.URL+"/missing"); err == nil {
+		t.Fatalf("Expected an error fetching a missing page")
+	}
+	if fresh := history(); fresh != first {
+		t.Errorf("Expected a new tab after a failed page, got history of %d", fresh)
+	}
+}
@@ -0,0 +1,155 @@
+package scraping_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	encoded, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("Error encoding %q: %v", text, err)
+	}
+	return encoded
+}
+
+func TestDecodeHTML(t *testing.T) {
+	longHead := "<html><head><title>Shop</title>" + strings.Repeat("<link rel=\"preload\" href=\"/a.js\">", 50)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+		encoding    string
+	}{
+		{
+			name:        "shift_jis from header",
+			body:        encode(t, japanese.ShiftJIS, "<p>日本語の商品</p>"),
+			contentType: "text/html; charset=Shift_JIS",
+			want:        "<p>日本語の商品</p>",
+			encoding:    "shift_jis",
+		},
+		{
+			name:     "windows-1251 from meta",
+			body:     encode(t, charmap.Windows1251, `<html><head><meta charset="windows-1251"></head><body>Привет</body></html>`),
+			want:     `<html><head><meta charset="windows-1251"></head><body>Привет</body></html>`,
+			encoding: "windows-1251",
+		},
+		{
+			name:        "gbk from http-equiv meta",
+			body:        encode(t, simplifiedchinese.GBK, `<meta http-equiv="Content-Type" content="text/html; charset=gbk"><p>价格</p>`),
+			contentType: "text/html",
+			want:        `<meta http-equiv="Content-Type" content="text/html; charset=gbk"><p>价格</p>`,
+			encoding:    "gbk",
+		},
+		{
+			name:     "meta after the prescanned bytes",
+			body:     encode(t, japanese.EUCJP, longHead+`<meta charset="euc-jp"></head><body>価格</body>`),
+			want:     longHead + `<meta charset="euc-jp"></head><body>価格</body>`,
+			encoding: "euc-jp",
+		},
+		{
+			name:        "iso-8859-1",
+			body:        encode(t, charmap.ISO8859_1, "<p>Café</p>"),
+			contentType: "text/html; charset=ISO-8859-1",
+			want:        "<p>Café</p>",
+			encoding:    "windows-1252",
+		},
+		{
+			name:        "byte order mark beats header",
+			body:        append([]byte{0xEF, 0xBB, 0xBF}, "<p>Café</p>"...),
+			contentType: "text/html; charset=ISO-8859-1",
+			want:        "<p>Café</p>",
+			encoding:    "utf-8",
+		},
+		{
+			name:     "undeclared utf-8",
+			body:     []byte(longHead + "<p>Café</p>"),
+			want:     longHead + "<p>Café</p>",
+			encoding: "utf-8",
+		},
+		{
+			name:     "undeclared legacy encoding",
+			body:     encode(t, charmap.Windows1252, "<p>Café</p>"),
+			want:     "<p>Café</p>",
+			encoding: "windows-1252",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, name, err := scraping.DecodeHTML(tt.body, tt.contentType)
+			if err != nil {
+				t.Fatalf("Error decoding: %v", err)
+			}
+			if string(decoded) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, decoded)
+			}
+			if name != tt.encoding {
+				t.Errorf("Expected encoding %q, got %q", tt.encoding, name)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherDecodesPages(t *testing.T) {
+	page := encode(t, japanese.ShiftJIS, "<html><body><h1>限定セール</h1></body></html>")
+	image := []byte{0x89, 'P', 'N', 'G', 0xFF, 0xFE}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+		w.Write(page)
+	}))
+	t.Cleanup(server.Close)
+
+	result, err := scraping.FetchHTTP(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	if result.Text() != "<html><body><h1>限定セール</h1></body></html>" {
+		t.Errorf("Expected the page to be decoded, got %q", result.Text())
+	}
+	if result.Charset != "Shift_JIS" {
+		t.Errorf("Expected the declared charset to be kept, got %q", result.Charset)
+	}
+
+	result, err = scraping.FetchHTTP(context.Background(), server.URL+"/image.png")
+	if err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	if !bytes.Equal(result.Body, image) {
+		t.Errorf("Expected binary content to be left as is, got %v", result.Body)
+	}
+}
+
+func TestFetchResultText(t *testing.T) {
+	// a custom fetcher returning the raw bytes of the page
+	result := &scraping.FetchResult{
+		Body:    encode(t, charmap.Windows1251, "<p>Цена</p>"),
+		Charset: "windows-1251",
+	}
+	if result.Text() != "<p>Цена</p>" {
+		t.Errorf("Expected the body to be decoded, got %q", result.Text())
+	}
+
+	result = &scraping.FetchResult{Body: []byte("<p>Цена</p>"), Charset: "windows-1251"}
+	if result.Text() != "<p>Цена</p>" {
+		t.Errorf("Expected a UTF-8 body to be left as is, got %q", result.Text())
+	}
+}
@@ -0,0 +1,133 @@
+package scraping_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestChunkDocument(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<html><body><ul>")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&sb, "<li>Product %d costs $%d</li>", i, i*10)
+	}
+	sb.WriteString("</ul><p>")
+	sb.WriteString(strings.Repeat("word ", 200))
+	sb.WriteString("</p></body></html>")
+
+	doc, err := scraping.GoQueryDocFromBody(sb.String())
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	const maxTokens = 60
+	chunks := scraping.ChunkDocument(doc, maxTokens, scraping.EstimateTokens)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the document to be split into several chunks, got %d", len(chunks))
+	}
+
+	joined := strings.Join(chunks, "")
+	for i := 0; i < 50; i++ {
+		item := fmt.Sprintf("<li>Product %d costs $%d</li>", i, i*10)
+		if !strings.Contains(joined, item) {
+			t.Errorf("Expected list item %d to be kept whole in a chunk", i)
+		}
+	}
+	if strings.Count(joined, "word") != 200 {
+		t.Errorf("Expected all words of the long paragraph to be kept, got %d", strings.Count(joined, "word"))
+	}
+
+	for i, chunk := range chunks {
+		if tokens := scraping.EstimateTokens(chunk); tokens > maxTokens {
+			t.Errorf("Chunk %d has %d tokens which exceeds the budget of %d", i, tokens, maxTokens)
+		}
+	}
+}
+
+func TestChunkDocumentSmall(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody("<html><body><h1>Example Domain</h1></body></html>")
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+	chunks := scraping.ChunkDocument(doc, 1000, scraping.EstimateTokens)
+	if len(chunks) != 1 || chunks[0] != "<h1>Example Domain</h1>" {
+		t.Errorf("Expected a single chunk, got %q", chunks)
+	}
+}
+
+func TestChunkDocumentTable(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString(`<html><body><table class="prices"><tbody>`)
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&sb, "<tr><td>Product %d</td><td>$%d</td></tr>", i, i*10)
+	}
+	sb.WriteString("</tbody></table></body></html>")
+	doc, err := scraping.GoQueryDocFromBody(sb.String())
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	const maxTokens = 100
+	chunks := scraping.ChunkDocument(doc, maxTokens, scraping.EstimateTokens)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the table to be split into several chunks, got %d", len(chunks))
+	}
+
+	var markdown strings.Builder
+	for i, chunk := range chunks {
+		if !strings.HasPrefix(chunk, `<table class="prices"><tbody><tr>`) || !strings.HasSuffix(chunk, "</tr></tbody></table>") {
+			t.Errorf("Expected chunk %d to reopen the table, got %s", i, chunk)
+		}
+		if tokens := scraping.EstimateTokens(chunk); tokens > maxTokens {
+			t.Errorf("Chunk %d has %d tokens which exceeds the budget of %d", i, tokens, maxTokens)
+		}
+		converted, err := scraping.HTMLToMarkdown(chunk)
+		if err != nil {
+			t.Fatalf("Error converting chunk %d: %v", i, err)
+		}
+		markdown.WriteString(converted + "\n")
+	}
+	// names and prices stay paired in rows once converted
+	for i := 0; i < 50; i++ {
+		if row := fmt.Sprintf("| Product %d | $%d |", i, i*10); !strings.Contains(markdown.String(), row) {
+			t.Errorf("Expected row %q in %s", row, markdown.String())
+		}
+	}
+}
+
+func TestChunkDocumentList(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString(`<html><body><p>Catalogue</p><ol start="3">`)
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&sb, "<li><b>Product %d</b> costs $%d</li>", i, i*10)
+	}
+	sb.WriteString("</ol><p>End</p></body></html>")
+	doc, err := scraping.GoQueryDocFromBody(sb.String())
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	chunks := scraping.ChunkDocument(doc, 80, scraping.EstimateTokens)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the list to be split into several chunks, got %d", len(chunks))
+	}
+	items := 0
+	for i, chunk := range chunks {
+		items += strings.Count(chunk, "<li>")
+		if strings.Contains(chunk, "<li>") && strings.Count(chunk, `<ol start="3">`) != 1 {
+			t.Errorf("Expected chunk %d to reopen the list once, got %s", i, chunk)
+		}
+		if strings.Count(chunk, "<ol") != strings.Count(chunk, "</ol>") {
+			t.Errorf("Expected chunk %d to close the list, got %s", i, chunk)
+		}
+	}
+	if items != 50 {
+		t.Errorf("Expected every item to be kept, got %d", items)
+	}
+	if !strings.HasPrefix(chunks[0], "<p>Catalogue</p><ol") || !strings.HasSuffix(chunks[len(chunks)-1], "</ol><p>End</p>") {
+		t.Errorf("Expected the content around the list to be kept outside of it, got %q", chunks)
+	}
+}
@@ -0,0 +1,99 @@
+package scraping_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func newFetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+		w.Header().Set("X-Served-By", "test")
+		fmt.Fprint(w, "<html><body>Hello</body></html>")
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchHTTP(t *testing.T) {
+	server := newFetchServer(t)
+
+	result, err := scraping.FetchHTTP(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	if result.Text() != "<html><body>Hello</body></html>" {
+		t.Errorf("Unexpected body: %s", result.Text())
+	}
+	if result.StatusCode != http.StatusOK || result.URL != server.URL+"/page" {
+		t.Errorf("Expected status 200 from the redirected URL, got %d from %s", result.StatusCode, result.URL)
+	}
+	if result.ContentType != "text/html" || result.Charset != "ISO-8859-1" {
+		t.Errorf("Unexpected content type %q and charset %q", result.ContentType, result.Charset)
+	}
+	if result.Header.Get("X-Served-By") != "test" || result.Elapsed <= 0 {
+		t.Errorf("Expected headers and timing to be recorded, got %+v", result)
+	}
+}
+
+func TestFetchHTTPErrorStatus(t *testing.T) {
+	server := newFetchServer(t)
+
+	_, err := scraping.FetchHTTP(context.Background(), server.URL+"/down")
+	var fetchErr *scraping.FetchError
+	if !errors.As(err, &fetchErr) || fetchErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected a FetchError with status 503, got %v", err)
+	}
+	if _, err := scraping.Fetch(context.Background(), server.URL+"/down"); !errors.As(err, &fetchErr) {
+		t.Errorf("Expected Fetch to return a FetchError, got %v", err)
+	}
+}
+
+func TestFromFetchFunc(t *testing.T) {
+	fetcher := scraping.FromFetchFunc(func(ctx context.Context, url string) (string, error) {
+		return "<p>" + url + "</p>", nil
+	})
+
+	result, err := fetcher.Fetch(context.Background(), "https://example.com")
+	if err != nil {
+		t.Fatalf("Error fetching: %v", err)
+	}
+	if result.Text() != "<p>https://example.com</p>" || result.URL != "https://example.com" || result.StatusCode != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestFetchZyteProxyRequiresKey(t *testing.T) {
+	t.Setenv("ZYTE_API_KEY", "")
+
+	fetchers := map[string]scraping.FetcherFunc{
+		"proxy":        scraping.FetchZyteProxy,
+		"browser html": scraping.FetchZyteProxyHTML,
+	}
+	for name, fetch := range fetchers {
+		t.Run(name, func(t *testing.T) {
+			result, err := fetch(context.Background(), "https://example.com")
+			if err == nil || result != nil {
+				t.Fatalf("Expected an error without an API key, got %+v", result)
+			}
+			if err.Error() != "ZYTE_API_KEY is not set in the environment" {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package scraping_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func newHeaderServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("User-Agent"), r.Header.Get("Accept-Language"))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		http.Redirect(w, r, "/account", http.StatusFound)
+	})
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			http.Error(w, "not logged in", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, cookie.Value)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("a", 100))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	server := newHeaderServer(t)
+
+	tests := []struct {
+		name       string
+		options    []scraping.HTTPOption
+		path       string
+		want       string
+		statusCode int
+		inError    string
+	}{
+		{
+			name: "default user agent",
+			path: "/headers",
+			want: scraping.DefaultUserAgent + "|",
+		},
+		{
+			name:    "headers",
+			options: []scraping.HTTPOption{scraping.WithHeader("User-Agent", "bot/1.0"), scraping.WithHeaders(http.Header{"accept-language": {"ja"}})},
+			path:    "/headers",
+			want:    "bot/1.0|ja",
+		},
+		{
+			name: "cookies kept across redirects",
+			path: "/login",
+			want: "abc",
+		},
+		{
+			name:    "cookies disabled",
+			options: []scraping.HTTPOption{scraping.WithCookieJar(nil)},
+			path:    "/login",
+			inError: "status code 401",
+		},
+		{
+			name:       "redirects not followed",
+			options:    []scraping.HTTPOption{scraping.WithMaxRedirects(0)},
+			path:       "/login",
+			statusCode: http.StatusFound,
+		},
+		{
+			name:    "redirect response failure status",
+			options: []scraping.HTTPOption{scraping.WithMaxRedirects(0), scraping.WithFailureStatus(func(code int) bool { return code >= 300 })},
+			path:    "/login",
+			inError: "status code 302",
+		},
+		{
+			name:    "too many redirects",
+			options: []scraping.HTTPOption{scraping.WithMaxRedirects(1)},
+			path:    "/loop",
+			inError: "stopped after 1 redirects",
+		},
+		{
+			name:    "body too large",
+			options: []scraping.HTTPOption{scraping.WithMaxBodySize(50)},
+			path:    "/large",
+			inError: "larger than 50 bytes",
+		},
+		{
+			name:    "body within limit",
+			options: []scraping.HTTPOption{scraping.WithMaxBodySize(100)},
+			path:    "/large",
+			want:    strings.Repeat("a", 100),
+		},
+		{
+			name:    "error status",
+			path:    "/missing",
+			inError: "status code 404",
+		},
+		{
+			name:       "error status allowed",
+			options:    []scraping.HTTPOption{scraping.WithFailureStatus(func(code int) bool { return code >= 500 })},
+			path:       "/missing",
+			want:       "not found\n",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher, err := scraping.NewHTTPFetcher(tt.options...)
+			if err != nil {
+				t.Fatalf("Error creating fetcher: %v", err)
+			}
+			result, err := fetcher.Fetch(context.Background(), server.URL+tt.path)
+			if tt.inError != "" {
+				var fetchErr *scraping.FetchError
+				if !errors.As(err, &fetchErr) || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected a FetchError containing %q, got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error fetching: %v", err)
+			}
+			if tt.want != "" && result.Text() != tt.want {
+				t.Errorf("Expected body %q, got %q", tt.want, result.Text())
+			}
+			if tt.statusCode != 0 && result.StatusCode != tt.statusCode {
+				t.Errorf("Expected status %d, got %d", tt.statusCode, result.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherRotation(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxy is sent the absolute URL of the page
+		proxied = append(proxied, r.URL.String())
+		fmt.Fprint(w, r.Header.Get("User-Agent"))
+	}))
+	t.Cleanup(proxy.Close)
+
+	fetcher, err := scraping.NewHTTPFetcher(
+		scraping.WithUserAgents("agent-a", "agent-b"),
+		scraping.WithProxies(proxy.URL, proxy.URL),
+	)
+	if err != nil {
+		t.Fatalf("Error creating fetcher: %v", err)
+	}
+
+	var agents []string
+	for range 3 {
+		result, err := fetcher.Fetch(context.Background(), "http://shop.example.com/item")
+		if err != nil {
+			t.Fatalf("Error fetching: %v", err)
+		}
+		agents = append(agents, result.Text())
+	}
+	if strings.Join(agents, ",") != "agent-a,agent-b,agent-a" {
+		t.Errorf("Expected user agents to rotate, got %v", agents)
+	}
+	if len(proxied) != 3 || proxied[0] != "http://shop.example.com/item" {
+		t.Errorf("Expected requests to go through the proxy, got %v", proxied)
+	}
+}
+
+func TestNewHTTPFetcherInvalidProxy(t *testing.T) {
+	_, err := scraping.NewHTTPFetcher(scraping.WithProxies("not a proxy"))
+	if err == nil || !strings.Contains(err.Error(), "invalid proxy URL") {
+		t.Errorf("Expected an invalid proxy error, got %v", err)
+	}
+}
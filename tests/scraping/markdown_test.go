@@ -0,0 +1,86 @@
+package scraping_test
+
+import (
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		markdown string
+		text     string
+	}{
+		{
+			name:     "headings and paragraphs",
+			html:     "<h1>Title</h1>\n<p>First\n   paragraph</p><h3>Sub <span>heading</span></h3><p>Line<br>break</p>",
+			markdown: "# Title\n\nFirst paragraph\n\n### Sub heading\n\nLine\nbreak",
+			text:     "Title\n\nFirst paragraph\n\nSub heading\n\nLine\nbreak",
+		},
+		{
+			name:     "inline formatting",
+			html:     `<p>A <strong>bold</strong> and <em>italic </em>word, <code>x := 1</code> and <a href="/shop">the shop</a>.</p>`,
+			markdown: "A **bold** and *italic* word, `x := 1` and [the shop](/shop).",
+			text:     "A bold and italic word, x := 1 and the shop.",
+		},
+		{
+			name:     "links without targets",
+			html:     `<p><a href="#top">Back</a> <a href="javascript:void(0)">Menu</a> <a>Plain</a></p>`,
+			markdown: "Back Menu Plain",
+			text:     "Back Menu Plain",
+		},
+		{
+			name:     "lists",
+			html:     `<ul><li>One</li><li>Two<ol><li>Nested</li><li>Again</li></ol></li></ul><ol start="3"><li>Three</li></ol>`,
+			markdown: "- One\n- Two\n  1. Nested\n  2. Again\n\n3. Three",
+			text:     "One\nTwo\nNested\nAgain\n\nThree",
+		},
+		{
+			name: "tables",
+			html: `<table><thead><tr><th>Name</th><th>Price</th></tr></thead>` +
+				`<tbody><tr><td>Widget</td><td>$10</td></tr><tr><td>A | B</td></tr></tbody></table>`,
+			markdown: "| Name | Price |\n| --- | --- |\n| Widget | $10 |\n| A \\| B |  |",
+			text:     "Name\tPrice\nWidget\t$10\nA | B",
+		},
+		{
+			name:     "quotes, code and rules",
+			html:     "<blockquote><p>Quoted</p><p>Twice</p></blockquote><hr><pre>func main() {\n\tfmt.Println()\n}</pre>",
+			markdown: "> Quoted\n>\n> Twice\n\n---\n\n```\nfunc main() {\n\tfmt.Println()\n}\n```",
+			text:     "Quoted\n\nTwice\n\nfunc main() {\n\tfmt.Println()\n}",
+		},
+		{
+			name:     "hidden content",
+			html:     `<html><head><title>Page</title></head><body><script>var x = 1</script><div>Visible<style>p {}</style></div></body></html>`,
+			markdown: "Visible",
+			text:     "Visible",
+		},
+		{
+			name:     "images",
+			html:     `<p><img src="/a.png" alt="A product"> <img src="/b.png"></p>`,
+			markdown: "![A product](/a.png)",
+			text:     "A product",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown, err := scraping.HTMLToMarkdown(tt.html)
+			if err != nil {
+				t.Fatalf("Error converting to Markdown: %v", err)
+			}
+			if markdown != tt.markdown {
+				t.Errorf("Unexpected Markdown\nexpected: %q\n     got: %q", tt.markdown, markdown)
+			}
+
+			text, err := scraping.HTMLToText(tt.html)
+			if err != nil {
+				t.Fatalf("Error converting to text: %v", err)
+			}
+			if text != tt.text {
+				t.Errorf("Unexpected text\nexpected: %q\n     got: %q", tt.text, text)
+			}
+		})
+	}
+}
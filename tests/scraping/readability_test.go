@@ -0,0 +1,80 @@
+package scraping_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+const articlePage = `<html><head><title>Widgets</title></head><body>
+<div id="cookie-banner">We use cookies to improve your experience, please accept them all.</div>
+<header class="site-header"><a href="/">Home</a> <a href="/shop">Shop</a> <a href="/blog">Blog</a></header>
+<nav><ul><li><a href="/a">A very long navigation link to the first section</a></li><li><a href="/b">Another long navigation link</a></li></ul></nav>
+<h1>How widgets are made</h1>
+<div class="layout">
+  <div class="post-body">
+    <p>Widgets are made in a factory, by skilled workers, using a process refined over many years.</p>
+    <p>First the raw materials are sourced, inspected, cleaned and sorted into batches by hand.</p>
+    <p>Then each batch is pressed, cut, polished and packed before being shipped to customers.</p>
+  </div>
+  <div class="sidebar"><h3>Related posts</h3><ul><li><a href="/x">Ten facts about gadgets that will surprise you</a></li><li><a href="/y">Why sprockets matter more than you think</a></li></ul></div>
+</div>
+<footer><p>Copyright 2024 Widget Co. All rights reserved, terms and conditions apply.</p></footer>
+</body></html>`
+
+func TestExtractMainContent(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody(articlePage)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	content := scraping.ExtractMainContent(doc)
+	text := content.Text()
+
+	for _, want := range []string{"How widgets are made", "made in a factory", "raw materials", "shipped to customers"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected main content to contain %q, got %q", want, text)
+		}
+	}
+	for _, unwanted := range []string{"cookies", "Shop", "navigation link", "Related posts", "sprockets", "Copyright"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("Expected %q to be removed, got %q", unwanted, text)
+		}
+	}
+
+	if !strings.Contains(doc.Text(), "Copyright") {
+		t.Errorf("Expected the original document to be unchanged")
+	}
+}
+
+func TestExtractMainContentFallback(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody(`<html><body><span>Short</span></body></html>`)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	content := scraping.ExtractMainContent(doc)
+	if strings.TrimSpace(content.Text()) != "Short" {
+		t.Errorf("Expected the whole document when there is no main content, got %q", content.Text())
+	}
+}
+
+func TestExtractMainContentKeepsBase(t *testing.T) {
+	page := strings.Replace(articlePage, "<head>", `<head><base href="https://cdn.example.com/blog/">`, 1)
+	page = strings.Replace(page, "by skilled workers", `by <a href="workers">skilled workers</a>`, 1)
+	doc, err := scraping.GoQueryDocFromBody(page)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	policy := scraping.DefaultStripPolicy
+	policy.BaseURL = "https://shop.example.com/posts/widgets"
+	html, err := scraping.GetDocumentHTML(scraping.StripWithPolicy(scraping.ExtractMainContent(doc), policy))
+	if err != nil {
+		t.Fatalf("Error getting HTML: %v", err)
+	}
+	if !strings.Contains(html, `href="https://cdn.example.com/blog/workers"`) {
+		t.Errorf("Expected links to resolve against the page's base, got %s", html)
+	}
+}
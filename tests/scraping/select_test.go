@@ -0,0 +1,120 @@
+package scraping_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+const shopPage = `<html><body>
+<header><a href="/">Shop</a></header>
+<div id="product-grid">
+  <div class="item">Widget <span class="price">$10</span></div>
+  <div class="item sponsored">Gadget <span class="price">$20</span></div>
+</div>
+<div class="reviews"><p>Great shop</p></div>
+<table class="prices"><tr><td>Bulk</td><td>$5</td></tr></table>
+</body></html>`
+
+func TestSelectContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []string
+		expected  string
+		inError   string
+	}{
+		{
+			name:      "css",
+			selectors: []string{"#product-grid"},
+			expected:  "Widget $10 Gadget $20",
+		},
+		{
+			name:      "xpath",
+			selectors: []string{"//table[@class='prices']"},
+			expected:  "Bulk$5",
+		},
+		{
+			name:      "xpath text nodes",
+			selectors: []string{"//span[@class='price']/text()"},
+			expected:  "$10$20",
+		},
+		{
+			name:      "parenthesised xpath",
+			selectors: []string{"(//div[@class='item'])[1]"},
+			expected:  "Widget $10",
+		},
+		{
+			name:      "document order and nested matches",
+			selectors: []string{".reviews", "#product-grid", ".item"},
+			expected:  "Widget $10 Gadget $20 Great shop",
+		},
+		{
+			name:      "no match",
+			selectors: []string{"#missing"},
+			inError:   "selectors matched no elements",
+		},
+		{
+			name:      "invalid css",
+			selectors: []string{"div[["},
+			inError:   `invalid selector "div[["`,
+		},
+		{
+			name:      "invalid xpath",
+			selectors: []string{"//div[@"},
+			inError:   `invalid selector "//div[@"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := scraping.GoQueryDocFromBody(shopPage)
+			if err != nil {
+				t.Fatalf("Error creating document: %v", err)
+			}
+
+			selected, err := scraping.SelectContent(doc, tt.selectors...)
+			if tt.inError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected error containing '%s', got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error selecting content: %v", err)
+			}
+			if text := strings.Join(strings.Fields(selected.Text()), " "); text != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, text)
+			}
+		})
+	}
+}
+
+func TestSelectContentNoMatch(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody(shopPage)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+	if _, err := scraping.SelectContent(doc, "#missing", "//article"); !errors.Is(err, scraping.ErrNoMatch) {
+		t.Errorf("Expected ErrNoMatch, got %v", err)
+	}
+}
+
+func TestRemoveContent(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody(shopPage)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	pruned, err := scraping.RemoveContent(doc, "header", ".sponsored", "//div[@class='reviews']", "//table")
+	if err != nil {
+		t.Fatalf("Error removing content: %v", err)
+	}
+	if text := strings.Join(strings.Fields(pruned.Text()), " "); text != "Widget $10" {
+		t.Errorf("Expected only the first item to remain, got %q", text)
+	}
+	if !strings.Contains(doc.Text(), "Gadget") {
+		t.Errorf("Expected the original document to be unchanged")
+	}
+}
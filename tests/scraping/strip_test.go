@@ -0,0 +1,142 @@
+package scraping_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samredway/scrapeai/scraping"
+)
+
+const productCard = `<html><head><script>track()</script><style>.a {}</style></head><body>
+<div class="card" style="color: red" data-id="42">
+  <a href="/products/widget" class="link" aria-label="Widget details"><img src="img/widget.png" alt="Widget" class="thumb" data-src="lazy.png"></a>
+  <h2 class="title">Widget</h2>
+  <time datetime="2024-07-18">July 18</time>
+  <button aria-label="Add to basket" onclick="add()"></button>
+  <span class="spacer"></span>
+  <a href="https://other.example.com/x">Elsewhere</a>
+  <a href="#reviews">Reviews</a>
+</div>
+</body></html>`
+
+func TestStripWithPolicy(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody(productCard)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	policy := scraping.DefaultStripPolicy
+	policy.BaseURL = "https://shop.example.com/catalogue/page"
+	html, err := scraping.GetDocumentHTML(scraping.StripWithPolicy(doc, policy))
+	if err != nil {
+		t.Fatalf("Error getting HTML: %v", err)
+	}
+
+	for _, want := range []string{
+		`<a href="https://shop.example.com/products/widget" aria-label="Widget details">`,
+		`<img src="https://shop.example.com/catalogue/img/widget.png" alt="Widget"/>`,
+		`<h2>Widget</h2>`,
+		`<time datetime="2024-07-18">July 18</time>`,
+		`<button aria-label="Add to basket"></button>`,
+		`<a href="https://other.example.com/x">Elsewhere</a>`,
+		`<a href="#reviews">Reviews</a>`,
+		`<div>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected stripped HTML to contain %s, got %s", want, html)
+		}
+	}
+	for _, unwanted := range []string{"class=", "style=", "data-", "onclick", "spacer", "<script", "<style"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("Expected %s to be stripped, got %s", unwanted, html)
+		}
+	}
+}
+
+func TestStripWithPolicyCustom(t *testing.T) {
+	doc, err := scraping.GoQueryDocFromBody(
+		`<html><head><base href="https://cdn.example.com/assets/"></head><body>` +
+			`<p class="price" data-sku="W1">$10</p><img data-src="widget.png"><img src="ignored.png"></body></html>`)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	policy := scraping.StripPolicy{
+		Attributes:     []string{"data-sku", "data-src"},
+		RemoveElements: []string{"img[src]"},
+		KeepEmpty:      []string{"img"},
+		BaseURL:        "https://shop.example.com/",
+	}
+	html, err := scraping.GetDocumentHTML(scraping.StripWithPolicy(doc, policy))
+	if err != nil {
+		t.Fatalf("Error getting HTML: %v", err)
+	}
+
+	expected := `<p data-sku="W1">$10</p><img data-src="https://cdn.example.com/assets/widget.png"/>`
+	if !strings.Contains(html, expected) {
+		t.Errorf("Expected stripped HTML to contain %s, got %s", expected, html)
+	}
+	if strings.Contains(html, "ignored.png") {
+		t.Errorf("Expected removed elements to be dropped, got %s", html)
+	}
+}
+
+func TestStripWithPolicyInlineData(t *testing.T) {
+	payload := strings.Repeat("R0lGODlhAQABAIAAAP", 500)
+	doc, err := scraping.GoQueryDocFromBody(`<html><body><p>Widget</p>` +
+		`<img src="data:image/gif;base64,` + payload + `" alt="Photo">` +
+		`<video poster="blob:https://shop.example.com/1234"></video>` +
+		`<a href=" DATA:text/html,hello">Link</a></body></html>`)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+
+	html, err := scraping.GetDocumentHTML(scraping.StripWithPolicy(doc, scraping.DefaultStripPolicy))
+	if err != nil {
+		t.Fatalf("Error getting HTML: %v", err)
+	}
+	for _, unwanted := range []string{payload[:20], "data:", "DATA:", "blob:"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("Expected inline data to be dropped, got %s", html)
+		}
+	}
+	if !strings.Contains(html, `<img alt="Photo"/>`) || len(html) > 300 {
+		t.Errorf("Expected the image to be kept without its data, got %s", html)
+	}
+
+	// unstripped pages are converted without their inline data too
+	markdown, err := scraping.HTMLToMarkdown(`<img src="data:image/gif;base64,` + payload + `" alt="Photo">`)
+	if err != nil {
+		t.Fatalf("Error converting to Markdown: %v", err)
+	}
+	if strings.Contains(markdown, "data:") || !strings.Contains(markdown, "Photo") {
+		t.Errorf("Expected Markdown without inline data, got %q", markdown)
+	}
+}
+
+func TestStripPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  scraping.StripPolicy
+		inError string
+	}{
+		{name: "default", policy: scraping.DefaultStripPolicy},
+		{name: "invalid remove selector", policy: scraping.StripPolicy{RemoveElements: []string{"script", "div[["}}, inError: `"div[["`},
+		{name: "invalid keep selector", policy: scraping.StripPolicy{KeepEmpty: []string{"img:nope"}}, inError: `"img:nope"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.inError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.inError) {
+				t.Errorf("Expected an error containing %s, got %v", tt.inError, err)
+			}
+		})
+	}
+}
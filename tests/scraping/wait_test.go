@@ -0,0 +1,111 @@
+package scraping_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/samredway/scrapeai/scraping"
+)
+
+// a page which renders its results a second after loading, as a single page
+// app fetching them from an API would
+const delayedPage = `<html><body><div id="app">Loading</div><script>
+setTimeout(() => {
+	document.getElementById("app").innerHTML = '<ul id="results"><li>Widget</li><li>Gadget</li></ul>';
+}, 1000);
+</script></body></html>`
+
+func TestWaitConditions(t *testing.T) {
+	chrome := findChrome(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, delayedPage)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := scraping.NewBrowserPool(
+		scraping.WithBrowsers(1),
+		scraping.WithAllocatorOptions(append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(chrome))...),
+	)
+	defer pool.Close()
+
+	tests := []struct {
+		name     string
+		wait     []scraping.WaitCondition
+		rendered bool
+		maxTime  time.Duration
+		inError  string
+	}{
+		{
+			name:     "visible",
+			wait:     []scraping.WaitCondition{scraping.WaitVisible("#results")},
+			rendered: true,
+		},
+		{
+			name:     "xpath visible",
+			wait:     []scraping.WaitCondition{scraping.WaitVisible("//li[text()='Gadget']")},
+			rendered: true,
+		},
+		{
+			name:     "javascript predicate",
+			wait:     []scraping.WaitCondition{scraping.WaitJS("document.querySelectorAll('#results li').length == 2")},
+			rendered: true,
+		},
+		{
+			name:     "dom quiet",
+			wait:     []scraping.WaitCondition{scraping.WaitDOMQuiet(1500 * time.Millisecond)},
+			rendered: true,
+		},
+		{
+			name:    "network idle only",
+			wait:    []scraping.WaitCondition{scraping.WaitNetworkIdle(100 * time.Millisecond)},
+			maxTime: 900 * time.Millisecond,
+		},
+		{
+			name:     "any",
+			wait:     []scraping.WaitCondition{scraping.WaitAny(scraping.WaitVisible("#missing"), scraping.WaitVisible("#results"))},
+			rendered: true,
+		},
+		{
+			name:    "at most",
+			wait:    []scraping.WaitCondition{scraping.WaitAtMost(300*time.Millisecond, scraping.WaitVisible("#missing"))},
+			maxTime: 900 * time.Millisecond,
+		},
+		{
+			name:    "javascript error",
+			wait:    []scraping.WaitCondition{scraping.WaitJS("undefinedVariable.length")},
+			inError: "waiting for page",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			result, err := pool.Fetcher(scraping.WithWait(tt.wait...)).Fetch(ctx, server.URL)
+			elapsed := time.Since(start)
+			if tt.inError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.inError) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.inError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error fetching: %v", err)
+			}
+			if rendered := strings.Contains(result.Text(), "Gadget"); rendered != tt.rendered {
+				t.Errorf("Expected rendered to be %v, got %s", tt.rendered, result.Text())
+			}
+			if tt.maxTime > 0 && elapsed > tt.maxTime {
+				t.Errorf("Expected the page within %v, took %v", tt.maxTime, elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package tokenizer_test
+
+import (
+	"testing"
+
+	"github.com/samredway/scrapeai/tokenizer"
+)
+
+func TestEncode(t *testing.T) {
+	// expected token ids produced by OpenAI's tiktoken
+	tests := []struct {
+		encoding string
+		text     string
+		expected []int
+	}{
+		{tokenizer.Cl100kBase, "hello world", []int{15339, 1917}},
+		{tokenizer.Cl100kBase, "Hello, world!", []int{9906, 11, 1917, 0}},
+		{tokenizer.Cl100kBase, "  hello", []int{220, 24748}},
+		{tokenizer.O200kBase, "hello world", []int{24912, 2375}},
+		{tokenizer.O200kBase, "Hello, world!", []int{13225, 11, 2375, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding+" "+tt.text, func(t *testing.T) {
+			enc, err := tokenizer.GetEncoding(tt.encoding)
+			if err != nil {
+				t.Fatalf("Error loading encoding: %v", err)
+			}
+			got := enc.Encode(tt.text)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Expected %v, got %v", tt.expected, got)
+				}
+			}
+			if enc.Count(tt.text) != len(tt.expected) {
+				t.Errorf("Expected count %d, got %d", len(tt.expected), enc.Count(tt.text))
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Package tokenizer implements the byte pair encodings used by OpenAI models so
+// that request sizes can be measured before they are sent.
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go-loader/assets"
+)
+
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// Go's \s only matches ASCII whitespace so the tiktoken patterns are rewritten
+// with an explicit unicode whitespace class. The \s+(?!\S) alternative cannot be
+// expressed without lookahead and is handled in Encoding.split instead
+const ws = `\t\n\v\f\r \x{85}\p{Z}`
+
+var patterns = map[string]string{
+	Cl100kBase: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^` + ws + `\p{L}\p{N}]+[\r\n]*|[` + ws + `]*[\r\n]+|[` + ws + `]+`,
+	O200kBase: `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^` + ws + `\p{L}\p{N}]+[\r\n/]*|[` + ws + `]*[\r\n]+|[` + ws + `]+`,
+}
+
+// Encoding is a byte pair encoding loaded from a tiktoken rank file
+type Encoding struct {
+	Name    string
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*Encoding{}
+)
+
+// GetEncoding returns the named encoding, loading it on first use
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+	pattern, ok := patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %s", name)
+	}
+	contents, err := assets.Assets.ReadFile(name + ".tiktoken")
+	if err != nil {
+		return nil, fmt.Errorf("reading ranks for %s: %w", name, err)
+	}
+	ranks, err := parseRanks(contents)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ranks for %s: %w", name, err)
+	}
+	enc := &Encoding{Name: name, ranks: ranks, pattern: regexp.MustCompile(pattern)}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// parseRanks reads the tiktoken file format of one base64 token and its rank
+// per line
+func parseRanks(contents []byte) (map[string]int, error) {
+	ranks := make(map[string]int, 200_000)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		token, rank, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, err
+		}
+		r, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(decoded)] = r
+	}
+	return ranks, scanner.Err()
+}
+
+// Encode returns the token ids for the given text. Special tokens are not
+// recognised and are encoded as ordinary text
+func (e *Encoding) Encode(text string) []int {
+	tokens := []int{}
+	for _, piece := range e.split(text) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, e.bytePairEncode([]byte(piece))...)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens in the given text
+func (e *Encoding) Count(text string) int {
+	count := 0
+	for _, piece := range e.split(text) {
+		if _, ok := e.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(e.bytePairEncode([]byte(piece)))
+	}
+	return count
+}
+
+// split breaks text into the pieces which are encoded independently
+func (e *Encoding) split(text string) []string {
+	pieces := []string{}
+	for len(text) > 0 {
+		loc := e.pattern.FindStringIndex(text)
+		if loc == nil || loc[1] == 0 {
+			// every character matches one of the alternatives so this is
+			// only reachable on invalid input, consume a single rune
+			_, size := utf8.DecodeRuneInString(text)
+			loc = []int{0, size}
+		}
+		end := loc[1]
+		// emulate \s+(?!\S) by leaving the last whitespace character of a run
+		// to be joined with the word which follows it
+		if end < len(text) && isTrailingSpace(text[:end]) {
+			_, size := utf8.DecodeLastRuneInString(text[:end])
+			end -= size
+		}
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+	return pieces
+}
+
+// isTrailingSpace reports whether s is a run of two or more whitespace
+// characters without line breaks
+func isTrailingSpace(s string) bool {
+	if utf8.RuneCountInString(s) < 2 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsSpace(r) || r == '\r' || r == '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// bytePairEncode repeatedly merges the adjacent pair of parts with the lowest
+// rank until no more merges are possible
+func (e *Encoding) bytePairEncode(piece []byte) []int {
+	// boundaries of the current parts, part i is piece[bounds[i]:bounds[i+1]]
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		minRank, minIndex := -1, -1
+		for i := 0; i < len(bounds)-2; i++ {
+			rank, ok := e.ranks[string(piece[bounds[i]:bounds[i+2]])]
+			if ok && (minRank == -1 || rank < minRank) {
+				minRank, minIndex = rank, i
+			}
+		}
+		if minIndex == -1 {
+			break
+		}
+		bounds = append(bounds[:minIndex+1], bounds[minIndex+2:]...)
+	}
+
+	tokens := make([]int, 0, len(bounds)-1)
+	for i := 0; i < len(bounds)-1; i++ {
+		tokens = append(tokens, e.ranks[string(piece[bounds[i]:bounds[i+1]])])
+	}
+	return tokens
+}